@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// A practice session that serves one question at a time, chosen according to the student's estimated ability.
+type AdaptiveSession struct {
+	Session string
+	Kind    SectionKind
+	Asked   map[string]bool
+	// Holds the current question as the only question of its only section, so that answers can be parsed as they
+	// are for a full psychometry.
+	Psychometry Psychometry
+}
+
+type AdaptiveView struct {
+	Session  string
+	Kind     SectionKind
+	Estimate AbilityEstimate
+	// The next question to answer, or nil when every question in the bank has been asked.
+	Section *Section
+}
+
+var adaptiveSessions = map[string]*AdaptiveSession{}
+
+// Estimates the student's ability from their whole history in the domain, and chooses the next question to ask.
+func nextAdaptiveView(session *AdaptiveSession, student string) AdaptiveView {
+	parameters := calibrateItems(responses)
+
+	bank := []ItemParameters{}
+	candidates := []string{}
+	questions := map[string]Question{}
+	for _, section := range bankSections() {
+		if section.Kind != session.Kind {
+			continue
+		}
+
+		for _, question := range section.Questions {
+			bank = append(bank, itemParameters(parameters, question.ID))
+			questions[question.ID] = question
+			if !session.Asked[question.ID] {
+				candidates = append(candidates, question.ID)
+			}
+		}
+	}
+
+	estimate := estimateStudentAbility(parameters, bank, studentResponses(student, session.Kind))
+	view := AdaptiveView{Session: session.Session, Kind: session.Kind, Estimate: estimate}
+
+	id, ok := selectNextItem(parameters, candidates, estimate.Theta)
+	if !ok {
+		session.Psychometry = Psychometry{}
+		return view
+	}
+
+	section := withPassages(Section{
+		Kind:      session.Kind,
+		Index:     0,
+		IsCounted: true,
+		Questions: []Question{questions[id]},
+	}, bankSections())
+	session.Psychometry = Psychometry{Sections: []Section{section}}
+	view.Section = &section
+
+	return view
+}
+
+func registerAdaptive(e *echo.Echo) {
+	e.GET("/adaptive", func(c echo.Context) error {
+		req := c.Request()
+		if err := req.ParseForm(); err != nil {
+			return err
+		}
+
+		kind := SectionKind(req.Form.Get("kind"))
+		if _, ok := sectionTopics[kind]; !ok {
+			return c.Render(http.StatusOK, "adaptive-options-page", []SectionKind{V, Q, E})
+		}
+
+		session := &AdaptiveSession{
+			Session: uuid.New().String(),
+			Kind:    kind,
+			Asked:   map[string]bool{},
+		}
+		adaptiveSessions[session.Session] = session
+
+		return c.Render(http.StatusOK, "adaptive-page", nextAdaptiveView(session, studentID(c)))
+	})
+
+	e.POST("/adaptive/answers", func(c echo.Context) error {
+		req := c.Request()
+		if err := req.ParseForm(); err != nil {
+			return err
+		}
+
+		session, ok := adaptiveSessions[req.Form.Get("session")]
+		if !ok || len(session.Psychometry.Sections) == 0 {
+			// TODO: handle this
+			return errors.New("invalid session")
+		}
+
+		answers, err := ParsePsychometryAnswers(req.Form, session.Psychometry, Shuffle{})
+		if err != nil {
+			return err
+		}
+
+		student := studentID(c)
+		if err := recordResponses(student, session.Session, session.Psychometry, *answers); err != nil {
+			return err
+		}
+		session.Asked[session.Psychometry.Sections[0].Questions[0].ID] = true
+
+		return c.Render(http.StatusOK, "adaptive-question", nextAdaptiveView(session, student))
+	})
+}
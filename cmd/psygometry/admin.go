@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const adminUsername = "admin"
+
+// Pages for the content team. Protected with basic authentication, using the password from the `ADMIN_PASSWORD`
+// environment variable (when it is empty, nobody is let in).
+func registerAdmin(e *echo.Echo) {
+	admin := e.Group("/admin", middleware.BasicAuth(func(username string, password string, c echo.Context) (bool, error) {
+		expected := os.Getenv("ADMIN_PASSWORD")
+		if expected == "" {
+			return false, nil
+		}
+
+		usernameMatches := subtle.ConstantTimeCompare([]byte(username), []byte(adminUsername)) == 1
+		passwordMatches := subtle.ConstantTimeCompare([]byte(password), []byte(expected)) == 1
+		return usernameMatches && passwordMatches, nil
+	}))
+
+	admin.GET("/items", func(c echo.Context) error {
+		counted := fullAttemptResponses(responses, attempts)
+		statistics := computeItemStatistics(bankSections(), counted)
+		if c.QueryParam("pilot") != "" {
+			statistics = pilotItemStatistics(counted, pilotResponses)
+		}
+		sortItemStatistics(statistics)
+
+		if c.QueryParam("flagged") != "" {
+			flagged := []ItemStatistics{}
+			for _, item := range statistics {
+				if len(item.Flags) > 0 {
+					flagged = append(flagged, item)
+				}
+			}
+			statistics = flagged
+		}
+
+		page := ItemStatisticsPage{Items: statistics, FlagNames: itemFlagNames, TopicNames: topicNames}
+		return c.Render(http.StatusOK, "admin-items-page", page)
+	})
+
+	admin.GET("/items.csv", func(c echo.Context) error {
+		statistics := computeItemStatistics(bankSections(), fullAttemptResponses(responses, attempts))
+		sortItemStatistics(statistics)
+
+		c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="items.csv"`)
+		c.Response().WriteHeader(http.StatusOK)
+		return writeItemStatisticsCSV(c.Response(), statistics)
+	})
+
+	admin.GET("/word-lists", func(c echo.Context) error {
+		return c.Render(http.StatusOK, "admin-word-lists-page", wordListSections)
+	})
+
+	admin.POST("/word-lists", importWordList)
+
+	registerExamAuthoring(admin)
+	registerAnswerSheets(admin)
+	registerBooklets(admin)
+}
+
+type ItemStatisticsPage struct {
+	Items      []ItemStatistics
+	FlagNames  map[ItemFlag]string
+	TopicNames map[Topic]string
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// A student changing their answer to a question during a psychometry, from one option to another.
+//
+// Changes are kept after their session is gone, so they refer to the question by its ID rather than by its place in the
+// session, and keep whether each option was correct by the key the student answered.
+type AnswerChange struct {
+	Session    string
+	QuestionID string
+	// The options before and after the change, where -1 is no option.
+	From        int
+	To          int
+	FromCorrect bool
+	ToCorrect   bool
+	Time        time.Time
+}
+
+var answerChanges = []AnswerChange{}
+
+// Path of a JSON lines file that answer changes are persisted to, taken from the `ANSWER_CHANGES_PATH` environment
+// variable.
+//
+// When empty, answer changes are only kept in memory.
+func answerChangesPath() string {
+	return os.Getenv("ANSWER_CHANGES_PATH")
+}
+
+func recordAnswerChange(change AnswerChange) error {
+	answerChanges = append(answerChanges, change)
+
+	if path := answerChangesPath(); path != "" {
+		return appendJSONLines(path, []AnswerChange{change})
+	}
+	return nil
+}
+
+// How the answers changed during a psychometry turned out.
+type AnswerChangeSummary struct {
+	RightToWrong int
+	WrongToRight int
+	WrongToWrong int
+}
+
+func (s AnswerChangeSummary) Total() int {
+	return s.RightToWrong + s.WrongToRight + s.WrongToWrong
+}
+
+// Summarizes the changes made during a session. Choosing an option for the first time is not a change.
+//
+// Only the multiple-choice questions of counted sections are summarized, as the student is not told about the
+// experimental section, and the answers to other types of questions are not single options.
+func summarizeAnswerChanges(psychometry Psychometry, session string, changes []AnswerChange) AnswerChangeSummary {
+	summarized := map[string]bool{}
+	for _, section := range psychometry.Sections {
+		for _, question := range section.Questions {
+			if section.IsCounted && question.IsMultipleChoice() {
+				summarized[question.ID] = true
+			}
+		}
+	}
+
+	summary := AnswerChangeSummary{}
+	for _, change := range changes {
+		if change.Session != session || !summarized[change.QuestionID] || change.From < 0 || change.To < 0 {
+			continue
+		}
+
+		switch {
+		case change.FromCorrect:
+			summary.RightToWrong += 1
+		case change.ToCorrect:
+			summary.WrongToRight += 1
+		default:
+			summary.WrongToWrong += 1
+		}
+	}
+
+	return summary
+}
+
+func parseOption(raw string, question Question) (int, error) {
+	if raw == "" {
+		return -1, nil
+	}
+
+	option, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, DeformedIndex
+	}
+	if option < -1 || option >= len(question.Options) {
+		return 0, InvalidIndex
+	}
+
+	return option, nil
+}
+
+func registerAnswerChanges(e *echo.Echo) {
+	// Receives a beacon from the client whenever an answer is changed
+	e.POST("/answers/changes", func(c echo.Context) error {
+		req := c.Request()
+		if err := req.ParseForm(); err != nil {
+			return err
+		}
+
+		session := req.Form.Get("session")
+		state, ok := psychometries[session]
+		if !ok {
+			return echo.NewHTTPError(http.StatusNotFound, "invalid session")
+		}
+
+		// Changes are reported as displayed, and recorded with canonical indexes
+		key := req.Form.Get("key")
+		sIndex, qIndex, err := parseQuestionIndexes(splitFormKey(key), state.Psychometry, state.Shuffle)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if !state.Accepts(key) {
+			return echo.NewHTTPError(http.StatusConflict, "the section does not accept answers")
+		}
+		question := state.Shuffle.question(state.Psychometry, sIndex, qIndex)
+		if !question.IsMultipleChoice() {
+			return echo.NewHTTPError(http.StatusBadRequest, "only changes of multiple-choice answers are recorded")
+		}
+
+		from, err := parseOption(req.Form.Get("from"), question)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		to, err := parseOption(req.Form.Get("to"), question)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		section := state.Shuffle.section(sIndex)
+		change := AnswerChange{
+			Session:    session,
+			QuestionID: question.ID,
+			From:       state.Shuffle.option(section, qIndex, from),
+			To:         state.Shuffle.option(section, qIndex, to),
+			Time:       time.Now(),
+		}
+		correct := state.Psychometry.Sections[section].Questions[qIndex].CorrectOption
+		change.FromCorrect = change.From == correct
+		change.ToCorrect = change.To == correct
+		if err := recordAnswerChange(change); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	})
+}
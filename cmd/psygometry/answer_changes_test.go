@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Test: changes are classified by whether they moved towards or away from the correct option, and only the
+// multiple-choice questions of counted sections are summarized
+func TestSummarizeAnswerChanges(t *testing.T) {
+	psychometry := generateFakeData()
+	psychometry.Sections = append(psychometry.Sections,
+		Section{Kind: V, Questions: []Question{{ID: "experimental", Options: make([]string, 4)}}},
+		Section{Kind: Q, IsCounted: true, Questions: []Question{{ID: "multiple", Type: MultipleSelect, Options: make([]string, 4)}}},
+	)
+	id := psychometry.Sections[0].Questions[0].ID
+
+	changes := []AnswerChange{
+		{Session: "s", QuestionID: id, From: -1, To: 0, ToCorrect: true},
+		{Session: "s", QuestionID: id, From: 0, To: 1, FromCorrect: true},
+		{Session: "s", QuestionID: id, From: 1, To: 2},
+		{Session: "s", QuestionID: id, From: 2, To: 0, ToCorrect: true},
+		{Session: "s", QuestionID: "experimental", From: 0, To: 1, FromCorrect: true},
+		{Session: "s", QuestionID: "multiple", From: 0, To: 1, FromCorrect: true},
+		{Session: "other", QuestionID: id, From: 0, To: 1, FromCorrect: true},
+	}
+
+	summary := summarizeAnswerChanges(psychometry, "s", changes)
+
+	expected := AnswerChangeSummary{RightToWrong: 1, WrongToRight: 1, WrongToWrong: 1}
+	if summary != expected {
+		t.Errorf("expected %v, got %v", expected, summary)
+	}
+}
+
+// Test: changes are recorded with the question's ID and correctness, and only while the section accepts answers
+func TestRegisterAnswerChanges(t *testing.T) {
+	defer func(original map[string]*State, changes []AnswerChange) {
+		psychometries, answerChanges = original, changes
+	}(psychometries, answerChanges)
+	t.Setenv("ANSWER_CHANGES_PATH", "")
+	answerChanges = []AnswerChange{}
+
+	state := newState("s", generateFakeData())
+	if err := state.Transition(Submit); err != nil {
+		t.Fatal(err)
+	}
+	psychometries = map[string]*State{"s": state}
+
+	e := echo.New()
+	registerAnswerChanges(e)
+	post := func(key string, from string, to string) int {
+		form := url.Values{"session": {"s"}, "key": {key}, "from": {from}, "to": {to}}
+		request := httptest.NewRequest(http.MethodPost, "/answers/changes", strings.NewReader(form.Encode()))
+		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	question := state.Shuffle.question(state.Psychometry, 0, 0)
+	if code := post("Sections[0][0]", "", strconv.Itoa(question.CorrectOption)); code != http.StatusNoContent {
+		t.Fatalf("expected the change to be recorded, got %d", code)
+	}
+	if len(answerChanges) != 1 {
+		t.Fatalf("expected a single change, got %v", answerChanges)
+	}
+	if change := answerChanges[0]; change.QuestionID != question.ID || !change.ToCorrect || change.FromCorrect {
+		t.Errorf("expected a change to the correct option of %s, got %v", question.ID, change)
+	}
+
+	if code := post("Sections[1][0]", "", "0"); code != http.StatusConflict {
+		t.Errorf("expected a change to another section to be rejected, got %d", code)
+	}
+	if err := state.Transition(Review); err != nil {
+		t.Fatal(err)
+	}
+	if code := post("Sections[0][0]", "", "0"); code != http.StatusConflict {
+		t.Errorf("expected a change while reviewing to be rejected, got %d", code)
+	}
+	if len(answerChanges) != 1 {
+		t.Errorf("expected rejected changes not to be recorded, got %v", answerChanges)
+	}
+}
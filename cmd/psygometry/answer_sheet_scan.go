@@ -0,0 +1,531 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var UnreadableSheet = errors.New("unreadable answer sheet")
+
+// How dark a bubble must be to be read as filled, and how light to be read as empty. Bubbles in between, such as
+// ones that were erased or only marked, are flagged for confirmation.
+const (
+	filledDarkness = 0.6
+	emptyDarkness  = 0.25
+)
+
+// The most pixels a scan may have, well above an A4 page scanned at 300 DPI (about 9 million), so that a small file
+// cannot decode into an image that would not fit in memory.
+const maximumScanPixels = 20_000_000
+
+// A scanned page, with every pixel that is darker than the paper around it marked as dark.
+type scannedImage struct {
+	width  int
+	height int
+	dark   []bool
+}
+
+// Decodes a PNG or JPEG scan, separating its ink from its paper by the threshold that best splits its brightness
+// (Otsu's method), so that neither the lighting nor the pen used matter.
+func readScannedImage(r io.ReadSeeker) (scannedImage, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return scannedImage{}, fmt.Errorf("%w: %w", UnreadableSheet, err)
+	}
+	if config.Width*config.Height > maximumScanPixels {
+		return scannedImage{}, fmt.Errorf("%w: the scan has more than %d pixels", UnreadableSheet, maximumScanPixels)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return scannedImage{}, err
+	}
+
+	decoded, _, err := image.Decode(r)
+	if err != nil {
+		return scannedImage{}, fmt.Errorf("%w: %w", UnreadableSheet, err)
+	}
+
+	bounds := decoded.Bounds()
+	scanned := scannedImage{width: bounds.Dx(), height: bounds.Dy(), dark: make([]bool, bounds.Dx()*bounds.Dy())}
+	brightness := make([]uint8, len(scanned.dark))
+	histogram := [256]int{}
+	for y := 0; y < scanned.height; y++ {
+		for x := 0; x < scanned.width; x++ {
+			r, g, b, _ := decoded.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			value := uint8((299*r + 587*g + 114*b) / 1000 >> 8)
+			brightness[y*scanned.width+x] = value
+			histogram[value] += 1
+		}
+	}
+
+	threshold := otsuThreshold(histogram, len(brightness))
+	for i, value := range brightness {
+		scanned.dark[i] = int(value) <= threshold
+	}
+	return scanned, nil
+}
+
+// Returns the brightness that separates the histogram into two classes with the greatest variance between them.
+func otsuThreshold(histogram [256]int, total int) int {
+	sum := 0.0
+	for value, count := range histogram {
+		sum += float64(value * count)
+	}
+
+	best, threshold := -1.0, 127
+	backgroundSum, backgroundCount := 0.0, 0
+	for value, count := range histogram {
+		backgroundCount += count
+		backgroundSum += float64(value * count)
+		foregroundCount := total - backgroundCount
+		if backgroundCount == 0 || foregroundCount == 0 {
+			continue
+		}
+
+		difference := backgroundSum/float64(backgroundCount) - (sum-backgroundSum)/float64(foregroundCount)
+		variance := float64(backgroundCount) * float64(foregroundCount) * difference * difference
+		if variance > best {
+			best, threshold = variance, value
+		}
+	}
+	return threshold
+}
+
+func (s scannedImage) isDark(x int, y int) bool {
+	return x >= 0 && y >= 0 && x < s.width && y < s.height && s.dark[y*s.width+x]
+}
+
+// Finds the center of the largest solid square within the rectangle, which is where its marker is.
+func (s scannedImage) findMarker(left int, top int, right int, bottom int) ([2]float64, bool) {
+	visited := make([]bool, (right-left)*(bottom-top))
+	best, found := 0, false
+	var center [2]float64
+
+	for y := top; y < bottom; y++ {
+		for x := left; x < right; x++ {
+			if visited[(y-top)*(right-left)+x-left] || !s.isDark(x, y) {
+				continue
+			}
+
+			// Flood fills the dark pixels connected to this one
+			area, sumX, sumY := 0, 0, 0
+			minX, minY, maxX, maxY := x, y, x, y
+			stack := [][2]int{{x, y}}
+			visited[(y-top)*(right-left)+x-left] = true
+			for len(stack) > 0 {
+				p := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				area += 1
+				sumX += p[0]
+				sumY += p[1]
+				minX, minY, maxX, maxY = min(minX, p[0]), min(minY, p[1]), max(maxX, p[0]), max(maxY, p[1])
+
+				for _, n := range [][2]int{{p[0] + 1, p[1]}, {p[0] - 1, p[1]}, {p[0], p[1] + 1}, {p[0], p[1] - 1}} {
+					if n[0] < left || n[1] < top || n[0] >= right || n[1] >= bottom {
+						continue
+					}
+					if index := (n[1]-top)*(right-left) + n[0] - left; !visited[index] && s.isDark(n[0], n[1]) {
+						visited[index] = true
+						stack = append(stack, n)
+					}
+				}
+			}
+
+			// Squares fill most of their bounding box even when rotated slightly, unlike text and filled bubbles
+			width, height := maxX-minX+1, maxY-minY+1
+			squareness := float64(width) / float64(height)
+			fill := float64(area) / float64(width*height)
+			if area > best && squareness > 0.7 && squareness < 1.4 && fill > 0.85 {
+				best, found = area, true
+				center = [2]float64{float64(sumX) / float64(area), float64(sumY) / float64(area)}
+			}
+		}
+	}
+
+	return center, found && best >= 16
+}
+
+// Maps positions on the sheet to positions on its scan, by interpolating between the markers found on the scan.
+type sheetTransform [4][2]float64
+
+func (t sheetTransform) apply(x float64, y float64) (float64, float64) {
+	u := (x - markerCenters[0][0]) / (markerCenters[1][0] - markerCenters[0][0])
+	v := (y - markerCenters[0][1]) / (markerCenters[2][1] - markerCenters[0][1])
+
+	weights := [4]float64{(1 - u) * (1 - v), u * (1 - v), (1 - u) * v, u * v}
+	scanX, scanY := 0.0, 0.0
+	for i, weight := range weights {
+		scanX += weight * t[i][0]
+		scanY += weight * t[i][1]
+	}
+	return scanX, scanY
+}
+
+// The transform of the same scan, had the sheet been upside down.
+func (t sheetTransform) rotated() sheetTransform {
+	return sheetTransform{t[3], t[2], t[1], t[0]}
+}
+
+// Returns the part of the circle on the sheet that is dark on the scan, sampled on a grid within it.
+func (s scannedImage) darkness(t sheetTransform, x float64, y float64, radius float64) float64 {
+	step := radius / 5
+	dark, total := 0, 0
+	for dy := -radius; dy <= radius; dy += step {
+		for dx := -radius; dx <= radius; dx += step {
+			if dx*dx+dy*dy > radius*radius {
+				continue
+			}
+
+			scanX, scanY := t.apply(x+dx, y+dy)
+			total += 1
+			if s.isDark(int(math.Round(scanX)), int(math.Round(scanY))) {
+				dark += 1
+			}
+		}
+	}
+	return float64(dark) / float64(total)
+}
+
+// Locates the markers of the scan, and reads the sheet and page numbers from its code, whichever way up it was
+// scanned.
+func (s scannedImage) locateSheet() (sheetTransform, uint64, int, error) {
+	// Every marker is searched for in its own corner of the scan
+	var transform sheetTransform
+	for i := range markerCenters {
+		left, top := 0, 0
+		if i%2 == 1 {
+			left = s.width * 2 / 3
+		}
+		if i >= 2 {
+			top = s.height * 3 / 4
+		}
+
+		center, ok := s.findMarker(left, top, left+s.width/3, top+s.height/4)
+		if !ok {
+			return transform, 0, 0, fmt.Errorf("%w: an alignment marker is missing", UnreadableSheet)
+		}
+		transform[i] = center
+	}
+
+	for _, t := range []sheetTransform{transform, transform.rotated()} {
+		bits := []bool{}
+		for _, cell := range sheetCode(0, 1) {
+			bits = append(bits, s.darkness(t, cell.X+sheetCodeCell/2, cell.Y+sheetCodeCell/2, sheetCodeCell/3) > 0.5)
+		}
+		if number, page, ok := decodeSheetCode(bits); ok {
+			return t, number, page, nil
+		}
+	}
+	return transform, 0, 0, fmt.Errorf("%w: the sheet code cannot be read", UnreadableSheet)
+}
+
+// The options read from the bubbles of a single question.
+type BubbleReading struct {
+	Section  int
+	Question int
+	// How dark the bubble of every option is, from 0 to 1.
+	Darkness []float64
+	Options  []int
+	// Why the reading needs to be confirmed, or empty when it does not.
+	Flag string
+}
+
+func readBubbles(s scannedImage, t sheetTransform, page SheetPage, psychometry Psychometry) []BubbleReading {
+	readings := []BubbleReading{}
+	index := map[[2]int]int{}
+	for _, bubble := range page.Bubbles {
+		key := [2]int{bubble.Section, bubble.Question}
+		if _, ok := index[key]; !ok {
+			index[key] = len(readings)
+			readings = append(readings, BubbleReading{Section: bubble.Section, Question: bubble.Question, Options: []int{}})
+		}
+
+		// The outline of the bubble is left out, so that only its filling is measured
+		reading := &readings[index[key]]
+		reading.Darkness = append(reading.Darkness, s.darkness(t, bubble.X, bubble.Y, bubbleRadius*0.6))
+	}
+
+	for i := range readings {
+		reading := &readings[i]
+		uncertain := false
+		for option, darkness := range reading.Darkness {
+			if darkness >= filledDarkness {
+				reading.Options = append(reading.Options, option)
+			} else if darkness > emptyDarkness {
+				uncertain = true
+			}
+		}
+
+		question := psychometry.Sections[reading.Section].Questions[reading.Question]
+		if uncertain {
+			reading.Flag = "סימון לא ברור"
+		} else if len(reading.Options) > 1 && !question.IsMultipleSelect() {
+			reading.Flag = "יותר מתשובה אחת"
+		}
+	}
+	return readings
+}
+
+// The readings of every page of an answer sheet, waiting for its flagged bubbles to be confirmed.
+type SheetScan struct {
+	Sheet    AnswerSheet
+	Exam     Exam
+	Readings []BubbleReading
+}
+
+var sheetScans = map[string]SheetScan{}
+
+func (s SheetScan) Flagged() []FlaggedBubble {
+	flagged := []FlaggedBubble{}
+	for _, reading := range s.Readings {
+		if reading.Flag != "" {
+			question := s.Exam.Psychometry.Sections[reading.Section].Questions[reading.Question]
+			flagged = append(flagged, FlaggedBubble{Reading: reading, Question: question})
+		}
+	}
+	return flagged
+}
+
+// Builds the answers read from the sheet. Flagged questions that were not confirmed are left unanswered.
+func (s SheetScan) Answers() PsychometryAnswers {
+	answers := newPsychometryAnswers(s.Exam.Psychometry)
+	for _, reading := range s.Readings {
+		if reading.Flag != "" || len(reading.Options) == 0 {
+			continue
+		}
+
+		question := s.Exam.Psychometry.Sections[reading.Section].Questions[reading.Question]
+		if question.IsMultipleSelect() {
+			answers.Entries[reading.Section][reading.Question] = formatOptions(reading.Options)
+		} else {
+			answers.Sections[reading.Section][reading.Question] = reading.Options[0]
+		}
+	}
+	return answers
+}
+
+type FlaggedBubble struct {
+	Reading  BubbleReading
+	Question Question
+}
+
+// Name of the form field the question is confirmed in.
+func (f FlaggedBubble) Key() string {
+	return fmt.Sprintf("Sections[%d][%d]", f.Reading.Section, f.Reading.Question)
+}
+
+func (f FlaggedBubble) Number() int {
+	return f.Reading.Question + 1
+}
+
+func (f FlaggedBubble) SectionNumber() int {
+	return f.Reading.Section + 1
+}
+
+// The darkness of every option's bubble, as percentages, alongside the option's number.
+func (f FlaggedBubble) Options() []FlaggedOption {
+	options := []FlaggedOption{}
+	for option, darkness := range f.Reading.Darkness {
+		read := false
+		for _, o := range f.Reading.Options {
+			read = read || o == option
+		}
+		options = append(options, FlaggedOption{Index: option, Number: option + 1, Darkness: int(math.Round(darkness * 100)), Read: read})
+	}
+	return options
+}
+
+type FlaggedOption struct {
+	Index    int
+	Number   int
+	Darkness int
+	Read     bool
+}
+
+// Reads every page of a sheet from its scans, which may be in any order.
+func readAnswerSheet(pages []scannedImage) (SheetScan, error) {
+	scan := SheetScan{Readings: []BubbleReading{}}
+	read := map[int]bool{}
+	var layout []SheetPage
+
+	for _, scanned := range pages {
+		transform, number, page, err := scanned.locateSheet()
+		if err != nil {
+			return scan, err
+		}
+
+		id := formatSheetNumber(number)
+		if scan.Sheet.ID == "" {
+			sheet, ok := answerSheets[id]
+			if !ok {
+				return scan, fmt.Errorf("%w: unknown sheet %s", UnreadableSheet, id)
+			}
+			exam, ok := findExamVersion(sheet.ExamVersion)
+			if !ok {
+				return scan, fmt.Errorf("%w: unknown exam %s", UnreadableSheet, sheet.ExamVersion)
+			}
+			scan.Sheet, scan.Exam = sheet, exam
+			layout = layoutAnswerSheet(exam.Psychometry)
+		} else if id != scan.Sheet.ID {
+			return scan, fmt.Errorf("%w: pages of both %s and %s were scanned", UnreadableSheet, scan.Sheet.ID, id)
+		}
+
+		if page > len(layout) || read[page] {
+			return scan, fmt.Errorf("%w: page %d is not expected", UnreadableSheet, page)
+		}
+		read[page] = true
+		scan.Readings = append(scan.Readings, readBubbles(scanned, transform, layout[page-1], scan.Exam.Psychometry)...)
+	}
+
+	if len(read) != len(layout) {
+		return scan, fmt.Errorf("%w: %d of %d pages were scanned", UnreadableSheet, len(read), len(layout))
+	}
+	return scan, nil
+}
+
+// Scores the answers of a sheet, recording them as an attempt of the exam like one taken online.
+func scoreAnswerSheet(scan SheetScan) (*ScoreSummary, error) {
+	answers := scan.Answers()
+
+	// The essay is not on the sheet, so the writing section scores nothing
+	summary, err := CalculateScoreSummary(scan.Exam.Psychometry, answers)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordResponses(scan.Sheet.student(), scan.Sheet.ID, scan.Exam.Psychometry, answers); err != nil {
+		return nil, err
+	}
+
+	attempt := Attempt{
+		Student:     scan.Sheet.student(),
+		Name:        scan.Sheet.Name,
+		Session:     scan.Sheet.ID,
+		ExamVersion: scan.Exam.VersionID(),
+		Started:     scan.Sheet.Created,
+		Finished:    time.Now(),
+		Summary:     *summary,
+	}
+	if err := recordAttempt(attempt); err != nil {
+		return nil, err
+	}
+
+	delete(sheetScans, scan.Sheet.ID)
+	return summary, nil
+}
+
+func isAnswerSheetScored(id string) bool {
+	for _, attempt := range attempts {
+		if attempt.Session == id {
+			return true
+		}
+	}
+	return false
+}
+
+type AnswerSheetScore struct {
+	Sheet   AnswerSheet
+	Summary ScoreSummary
+}
+
+func scanAnswerSheet(c echo.Context) error {
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["pages"]) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "missing scans of the sheet")
+	}
+
+	pages := []scannedImage{}
+	for _, header := range form.File["pages"] {
+		if header.Size > maximumScanBytes {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "scan is too large")
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			return err
+		}
+		scanned, err := readScannedImage(file)
+		file.Close()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+		}
+		pages = append(pages, scanned)
+	}
+
+	scan, err := readAnswerSheet(pages)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	if isAnswerSheetScored(scan.Sheet.ID) {
+		return echo.NewHTTPError(http.StatusConflict, "the sheet was already scored")
+	}
+
+	if len(scan.Flagged()) > 0 {
+		sheetScans[scan.Sheet.ID] = scan
+		return c.Render(http.StatusOK, "answer-sheet-confirmation", scan)
+	}
+
+	summary, err := scoreAnswerSheet(scan)
+	if err != nil {
+		return err
+	}
+	return c.Render(http.StatusCreated, "answer-sheet-score", AnswerSheetScore{Sheet: scan.Sheet, Summary: *summary})
+}
+
+// Applies the options chosen for every flagged question, and scores the sheet.
+func confirmAnswerSheet(c echo.Context) error {
+	scan, ok := sheetScans[c.Param("id")]
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "the sheet is not waiting for confirmation")
+	}
+	if isAnswerSheetScored(scan.Sheet.ID) {
+		return echo.NewHTTPError(http.StatusConflict, "the sheet was already scored")
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return err
+	}
+
+	readings := append([]BubbleReading{}, scan.Readings...)
+	for i, reading := range readings {
+		if reading.Flag == "" {
+			continue
+		}
+
+		flagged := FlaggedBubble{Reading: reading, Question: scan.Exam.Psychometry.Sections[reading.Section].Questions[reading.Question]}
+		options := []int{}
+		for _, value := range form[flagged.Key()] {
+			if value == "" {
+				continue
+			}
+			option, err := strconv.Atoi(value)
+			if err != nil || option < 0 || option >= len(reading.Darkness) {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid option")
+			}
+			options = append(options, option)
+		}
+		if len(options) > 1 && !flagged.Question.IsMultipleSelect() {
+			return echo.NewHTTPError(http.StatusBadRequest, "only one option can be chosen")
+		}
+
+		readings[i].Options = options
+		readings[i].Flag = ""
+	}
+	scan.Readings = readings
+
+	summary, err := scoreAnswerSheet(scan)
+	if err != nil {
+		return err
+	}
+	return c.Render(http.StatusCreated, "answer-sheet-score", AnswerSheetScore{Sheet: scan.Sheet, Summary: *summary})
+}
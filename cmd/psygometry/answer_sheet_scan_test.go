@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Resolution of the drawn scans, in pixels per millimeter (about 75 DPI).
+const scanScale = 3
+
+// Draws a page of a sheet the way it is printed, with the bubbles in `marks` filled from the right by the given part
+// of their width, as a scan turned by `angle` radians around its center.
+func drawSheetPage(t *testing.T, view AnswerSheetPageView, marks map[[3]int]float64, angle float64) []byte {
+	width, height := (sheetWidth+20)*scanScale, (sheetHeight+20)*scanScale
+	scan := image.NewGray(image.Rect(0, 0, width, height))
+
+	sin, cos := math.Sincos(angle)
+	for py := 0; py < height; py++ {
+		for px := 0; px < width; px++ {
+			dx, dy := float64(px-width/2)/scanScale, float64(py-height/2)/scanScale
+			x, y := sheetWidth/2+cos*dx+sin*dy, sheetHeight/2-sin*dx+cos*dy
+
+			scan.SetGray(px, py, color.Gray{Y: 245})
+			if isPrintedDark(view, marks, x, y) {
+				scan.SetGray(px, py, color.Gray{Y: 20})
+			}
+		}
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := png.Encode(buffer, scan); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func isPrintedDark(view AnswerSheetPageView, marks map[[3]int]float64, x float64, y float64) bool {
+	for _, marker := range view.Markers() {
+		if x >= marker[0] && x < marker[0]+markerSize && y >= marker[1] && y < marker[1]+markerSize {
+			return true
+		}
+	}
+	for _, cell := range view.Code() {
+		if cell.Dark && x >= cell.X && x < cell.X+sheetCodeCell && y >= cell.Y && y < cell.Y+sheetCodeCell {
+			return true
+		}
+	}
+
+	for _, bubble := range view.Page.Bubbles {
+		distance := math.Hypot(x-bubble.X, y-bubble.Y)
+		if distance > bubbleRadius+0.2 {
+			continue
+		}
+		if math.Abs(distance-bubbleRadius) < 0.15 {
+			return true
+		}
+
+		filled := marks[[3]int{bubble.Section, bubble.Question, bubble.Option}]
+		return distance < bubbleRadius && bubble.X+bubbleRadius-x < 2*bubbleRadius*filled
+	}
+	return false
+}
+
+// Sets up a published exam with a single answer sheet, restoring everything the tests change when they end.
+func withAnswerSheet(t *testing.T) AnswerSheetPageView {
+	originalExams, originalSheets, originalScans := exams, answerSheets, sheetScans
+	originalAttempts, originalResponses, originalPilot, originalSchedules := attempts, responses, pilotResponses, reviewSchedules
+	t.Cleanup(func() {
+		exams, answerSheets, sheetScans = originalExams, originalSheets, originalScans
+		attempts, responses, pilotResponses, reviewSchedules = originalAttempts, originalResponses, originalPilot, originalSchedules
+	})
+
+	exam := Exam{ID: "fake", Version: 1, Psychometry: generateFakeData()}
+	exams = []Exam{exam}
+	sheet := AnswerSheet{ID: formatSheetNumber(0xfedcba9876), ExamVersion: exam.VersionID(), Name: "דנה", Created: time.Now()}
+	answerSheets, sheetScans = map[string]AnswerSheet{sheet.ID: sheet}, map[string]SheetScan{}
+	attempts, responses, pilotResponses, reviewSchedules = []Attempt{}, []Response{}, []Response{}, map[string]map[string]*ReviewCard{}
+
+	pages := newAnswerSheetsPage(exam, []AnswerSheet{sheet}).Pages
+	if len(pages) != 1 {
+		t.Fatalf("expected the fake exam to fit in a single page, got %d", len(pages))
+	}
+	return pages[0]
+}
+
+func readDrawnSheet(t *testing.T, page []byte) (SheetScan, error) {
+	scanned, err := readScannedImage(bytes.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return readAnswerSheet([]scannedImage{scanned})
+}
+
+// Test: filled bubbles are read whichever way the sheet was scanned, unclear or repeated marks are flagged, and
+// unknown sheets and oversized scans are rejected
+func TestReadAnswerSheet(t *testing.T) {
+	view := withAnswerSheet(t)
+	marks := map[[3]int]float64{
+		{0, 0, 2}: 1,
+		{0, 1, 0}: 0.5,
+		{1, 0, 0}: 1,
+		{1, 0, 1}: 1,
+		{1, 1, 3}: 1,
+	}
+
+	for _, angle := range []float64{0, 0.03, math.Pi - 0.02} {
+		scan, err := readDrawnSheet(t, drawSheetPage(t, view, marks, angle))
+		if err != nil {
+			t.Fatalf("angle %v: %v", angle, err)
+		}
+		if scan.Sheet.ID != view.Sheet.ID {
+			t.Errorf("angle %v: expected sheet %s, got %s", angle, view.Sheet.ID, scan.Sheet.ID)
+		}
+
+		answers := scan.Answers()
+		if answers.Sections[0][0] != 2 || answers.Sections[1][1] != 3 || answers.Sections[0][1] != -1 || answers.Sections[1][0] != -1 {
+			t.Errorf("angle %v: expected only the clear marks to be answers, got %v", angle, answers.Sections)
+		}
+
+		flagged := scan.Flagged()
+		if len(flagged) != 2 || flagged[0].Key() != "Sections[0][1]" || flagged[1].Key() != "Sections[1][0]" {
+			t.Errorf("angle %v: expected the half-filled and the repeated marks to be flagged, got %v", angle, flagged)
+		}
+	}
+
+	answerSheets = map[string]AnswerSheet{}
+	if _, err := readDrawnSheet(t, drawSheetPage(t, view, marks, 0)); err == nil {
+		t.Error("expected a sheet that was not printed to be rejected")
+	}
+
+	// A blank image compresses to a small file whatever its size, so its size is checked before it is decoded
+	huge := &bytes.Buffer{}
+	if err := png.Encode(huge, image.NewGray(image.Rect(0, 0, 5000, 5000))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readScannedImage(bytes.NewReader(huge.Bytes())); err == nil {
+		t.Error("expected a scan with too many pixels to be rejected")
+	}
+}
+
+// Test: a clear sheet is scored as soon as it is scanned, a flagged one once it is confirmed, and neither twice
+func TestScanAnswerSheet(t *testing.T) {
+	view := withAnswerSheet(t)
+
+	e := echo.New()
+	e.Renderer = &Template{templates: template.Must(template.ParseGlob("public/views/*.html"))}
+	registerAnswerSheets(e.Group("/admin"))
+
+	scan := func(page []byte) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("pages", "scan.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(page)
+		writer.Close()
+
+		request := httptest.NewRequest(http.MethodPost, "/admin/answer-sheets/scans", body)
+		request.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	correct := map[[3]int]float64{}
+	for _, bubble := range view.Page.Bubbles {
+		if bubble.Option == generateFakeData().Sections[bubble.Section].Questions[bubble.Question].CorrectOption {
+			correct[[3]int{bubble.Section, bubble.Question, bubble.Option}] = 1
+		}
+	}
+	page := drawSheetPage(t, view, correct, 0.01)
+
+	flaggedMarks := map[[3]int]float64{{0, 0, 1}: 0.45}
+	if recorder := scan(drawSheetPage(t, view, flaggedMarks, 0)); recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `name="Sections[0][0]"`) {
+		t.Fatalf("expected the unclear mark to be confirmed first, got %d: %s", recorder.Code, recorder.Body)
+	}
+	if len(attempts) != 0 {
+		t.Fatal("expected nothing to be scored before the confirmation")
+	}
+
+	form := url.Values{"Sections[0][0]": {"1"}}
+	request := httptest.NewRequest(http.MethodPost, "/admin/answer-sheets/"+view.Sheet.ID+"/answers", strings.NewReader(form.Encode()))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("expected the confirmed sheet to be scored, got %d: %s", recorder.Code, recorder.Body)
+	}
+	if len(attempts) != 1 || attempts[0].Session != view.Sheet.ID || attempts[0].Student != view.Sheet.student() || attempts[0].Name != "דנה" || attempts[0].ExamVersion != "fake.v1" {
+		t.Errorf("expected an attempt of the sheet's student, got %v", attempts)
+	}
+	if responses[0].Option != 1 {
+		t.Errorf("expected the confirmed option to be recorded, got %v", responses[0])
+	}
+
+	if recorder := scan(page); recorder.Code != http.StatusConflict {
+		t.Errorf("expected a scored sheet not to be scored again, got %d", recorder.Code)
+	}
+
+	// Without its attempt, the sheet is scored right away when it is clear
+	attempts = []Attempt{}
+	if recorder := scan(page); recorder.Code != http.StatusCreated {
+		t.Fatalf("expected the clear sheet to be scored, got %d: %s", recorder.Code, recorder.Body)
+	}
+	if attempts[0].Summary.StaticScores.VRaw == 0 {
+		t.Errorf("expected the correct answers to be scored, got %v", attempts[0].Summary)
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// A printed answer sheet, handed to a single student of an in-class mock exam taken on paper.
+type AnswerSheet struct {
+	// A number of `sheetNumberBits` bits, in hexadecimal, which is printed on the sheet both as text and as its code.
+	ID          string
+	ExamVersion string
+	// Name of the student the sheet was printed for, or empty when it was printed blank.
+	Name    string
+	Created time.Time
+}
+
+// The student that the answers of the sheet are recorded for. Names are not student IDs, so every sheet is a student of
+// its own.
+func (s AnswerSheet) student() string {
+	return "sheet-" + s.ID
+}
+
+var answerSheets = map[string]AnswerSheet{}
+
+// Path of a JSON lines file that printed answer sheets are persisted to, taken from the `ANSWER_SHEETS_PATH`
+// environment variable, so that sheets can be scanned after the server restarts.
+//
+// When empty, answer sheets are only kept in memory.
+func answerSheetsPath() string {
+	return os.Getenv("ANSWER_SHEETS_PATH")
+}
+
+func recordAnswerSheets(sheets []AnswerSheet) error {
+	for _, sheet := range sheets {
+		answerSheets[sheet.ID] = sheet
+	}
+
+	if path := answerSheetsPath(); path != "" {
+		return appendJSONLines(path, sheets)
+	}
+	return nil
+}
+
+// The most sheets printed at once.
+const maximumAnswerSheets = 200
+
+// Creates a sheet for every student name, or `count` blank sheets when there are no names.
+func newAnswerSheets(exam Exam, names []string, count int, now time.Time) ([]AnswerSheet, error) {
+	if len(names) == 0 {
+		names = make([]string, count)
+	}
+	if len(names) == 0 || len(names) > maximumAnswerSheets {
+		return nil, fmt.Errorf("between 1 and %d sheets can be printed", maximumAnswerSheets)
+	}
+	if pages := len(layoutAnswerSheet(exam.Psychometry)); pages > maximumSheetPages {
+		return nil, fmt.Errorf("the exam takes %d pages of answer sheet, while at most %d fit in the sheet code", pages, maximumSheetPages)
+	}
+
+	sheets := []AnswerSheet{}
+	for _, name := range names {
+		id, err := newAnswerSheetID()
+		if err != nil {
+			return nil, err
+		}
+		sheets = append(sheets, AnswerSheet{ID: id, ExamVersion: exam.VersionID(), Name: name, Created: now})
+	}
+	return sheets, nil
+}
+
+func newAnswerSheetID() (string, error) {
+	for {
+		buffer := make([]byte, 8)
+		if _, err := rand.Read(buffer[8-sheetNumberBits/8:]); err != nil {
+			return "", err
+		}
+
+		id := formatSheetNumber(binary.BigEndian.Uint64(buffer))
+		if _, ok := answerSheets[id]; !ok {
+			return id, nil
+		}
+	}
+}
+
+func formatSheetNumber(number uint64) string {
+	return fmt.Sprintf("%0*x", sheetNumberBits/4, number)
+}
+
+// The layout of answer sheets, in millimeters from the top left corner of an A4 page.
+//
+// Sheets are read by locating their alignment markers, which are the only large solid squares on them, and reading
+// everything else relative to the markers, so that scans and photos need not be straight or at any resolution.
+const (
+	sheetWidth  = 210
+	sheetHeight = 297
+
+	markerSize  = 8
+	markerInset = 8
+
+	// The sheet code holds the sheet number, the page number, and a checksum of both, one bit per cell. Cells are
+	// spaced apart so that they are never mistaken for a marker.
+	sheetNumberBits   = 40
+	sheetPageBits     = 4
+	sheetChecksumBits = 8
+	sheetCodeBits     = sheetNumberBits + sheetPageBits + sheetChecksumBits
+	// Exams whose answer sheets would take more pages than the code can number are not printed.
+	maximumSheetPages = 1 << sheetPageBits
+	sheetCodeColumns  = 26
+	sheetCodeLeft     = 22
+	sheetCodeTop      = 20
+	sheetCodeCell     = 2.5
+	sheetCodePitch    = 3.5
+
+	gridTop       = 50
+	gridBottom    = 280
+	gridLeft      = 14
+	gridRight     = 196
+	rowHeight     = 5.5
+	numberWidth   = 8
+	optionSpacing = 6
+	columnGap     = 5
+	bubbleRadius  = 1.9
+)
+
+// Centers of the alignment markers, in the order top left, top right, bottom left, and bottom right.
+var markerCenters = [4][2]float64{
+	{markerInset + markerSize/2, markerInset + markerSize/2},
+	{sheetWidth - markerInset - markerSize/2, markerInset + markerSize/2},
+	{markerInset + markerSize/2, sheetHeight - markerInset - markerSize/2},
+	{sheetWidth - markerInset - markerSize/2, sheetHeight - markerInset - markerSize/2},
+}
+
+type SheetBubble struct {
+	Section  int
+	Question int
+	Option   int
+	X        float64
+	Y        float64
+}
+
+type SheetLabel struct {
+	X       float64
+	Y       float64
+	Text    string
+	Heading bool
+}
+
+// A single page of an answer sheet. Long exams take several pages, each with its own markers and code.
+type SheetPage struct {
+	Number  int
+	Bubbles []SheetBubble
+	Labels  []SheetLabel
+}
+
+// Lays out the bubbles of every question in the exam, a row per question and a column per option, with the
+// sections following each other in columns from right to left.
+//
+// Questions answered with a number cannot be bubbled, and are left for the booklet.
+func layoutAnswerSheet(psychometry Psychometry) []SheetPage {
+	options := 1
+	for _, section := range psychometry.Sections {
+		for _, question := range section.Questions {
+			options = max(options, len(question.Options))
+		}
+	}
+	width := numberWidth + float64(options)*optionSpacing
+
+	pages := []SheetPage{{Number: 1}}
+	right := float64(gridRight)
+	y := float64(gridTop)
+	nextRow := func() {
+		y += rowHeight
+		if y+rowHeight <= gridBottom {
+			return
+		}
+
+		y = gridTop
+		right -= width + columnGap
+		if right-width < gridLeft {
+			pages = append(pages, SheetPage{Number: len(pages) + 1})
+			right = gridRight
+		}
+	}
+
+	// Every column a section continues in starts with its name and option numbers
+	heading := func(i int, continued bool) {
+		page := &pages[len(pages)-1]
+		text := fmt.Sprintf("פרק %d", i+1)
+		if continued {
+			text += " (המשך)"
+		}
+		page.Labels = append(page.Labels, SheetLabel{X: right, Y: y + rowHeight - 1.5, Text: text, Heading: true})
+		nextRow()
+
+		page = &pages[len(pages)-1]
+		for option := 0; option < options; option++ {
+			x := right - numberWidth - optionSpacing/2 - float64(option)*optionSpacing
+			page.Labels = append(page.Labels, SheetLabel{X: x, Y: y + rowHeight - 1.5, Text: strconv.Itoa(option + 1)})
+		}
+		nextRow()
+	}
+
+	for i, section := range psychometry.Sections {
+		// A section starts in a new column rather than leave its name alone at the bottom of one
+		if y != gridTop && y+3*rowHeight > gridBottom {
+			y = gridBottom
+			nextRow()
+		}
+		heading(i, false)
+
+		for j, question := range section.Questions {
+			if y == gridTop {
+				heading(i, true)
+			}
+
+			page := &pages[len(pages)-1]
+			center := y + rowHeight/2
+			page.Labels = append(page.Labels, SheetLabel{X: right, Y: center + 1.2, Text: strconv.Itoa(j + 1)})
+
+			if question.IsNumericEntry() {
+				page.Labels = append(page.Labels, SheetLabel{X: right - numberWidth, Y: center + 1.2, Text: "בחוברת"})
+			} else {
+				for option := range question.Options {
+					x := right - numberWidth - optionSpacing/2 - float64(option)*optionSpacing
+					page.Bubbles = append(page.Bubbles, SheetBubble{Section: i, Question: j, Option: option, X: x, Y: center})
+				}
+			}
+			nextRow()
+		}
+	}
+
+	// The last row may have started a page of its own
+	if last := pages[len(pages)-1]; len(pages) > 1 && len(last.Labels) == 0 {
+		pages = pages[:len(pages)-1]
+	}
+	return pages
+}
+
+type SheetCell struct {
+	X    float64
+	Y    float64
+	Dark bool
+}
+
+// Encodes the sheet number and page number into the cells of the sheet code, from the most significant bit.
+func sheetCode(number uint64, page int) []SheetCell {
+	value := number<<sheetPageBits | uint64(page-1)
+	value = value<<sheetChecksumBits | uint64(sheetChecksum(value))
+
+	cells := make([]SheetCell, sheetCodeBits)
+	for i := range cells {
+		cells[i] = SheetCell{
+			X:    sheetCodeLeft + float64(i%sheetCodeColumns)*sheetCodePitch,
+			Y:    sheetCodeTop + float64(i/sheetCodeColumns)*sheetCodePitch,
+			Dark: value>>(sheetCodeBits-1-i)&1 == 1,
+		}
+	}
+	return cells
+}
+
+// Decodes the sheet number and page number from the bits of the sheet code, reporting whether its checksum matches.
+func decodeSheetCode(bits []bool) (uint64, int, bool) {
+	var value uint64
+	for _, bit := range bits {
+		value <<= 1
+		if bit {
+			value |= 1
+		}
+	}
+
+	checksum := uint8(value & (1<<sheetChecksumBits - 1))
+	value >>= sheetChecksumBits
+	if len(bits) != sheetCodeBits || sheetChecksum(value) != checksum {
+		return 0, 0, false
+	}
+	return value >> sheetPageBits, int(value&(1<<sheetPageBits-1)) + 1, true
+}
+
+func sheetChecksum(value uint64) uint8 {
+	buffer := make([]byte, 8)
+	binary.BigEndian.PutUint64(buffer, value)
+	return uint8(crc32.ChecksumIEEE(buffer))
+}
+
+// A single page of a printed sheet.
+type AnswerSheetPageView struct {
+	Sheet AnswerSheet
+	Page  SheetPage
+	Pages int
+}
+
+func (v AnswerSheetPageView) Code() []SheetCell {
+	number, _ := strconv.ParseUint(v.Sheet.ID, 16, 64)
+	return sheetCode(number, v.Page.Number)
+}
+
+func (v AnswerSheetPageView) Markers() [][2]float64 {
+	markers := [][2]float64{}
+	for _, center := range markerCenters {
+		markers = append(markers, [2]float64{center[0] - markerSize/2, center[1] - markerSize/2})
+	}
+	return markers
+}
+
+type AnswerSheetsPage struct {
+	Exam  Exam
+	Pages []AnswerSheetPageView
+}
+
+func newAnswerSheetsPage(exam Exam, sheets []AnswerSheet) AnswerSheetsPage {
+	layout := layoutAnswerSheet(exam.Psychometry)
+
+	page := AnswerSheetsPage{Exam: exam}
+	for _, sheet := range sheets {
+		for _, sheetPage := range layout {
+			page.Pages = append(page.Pages, AnswerSheetPageView{Sheet: sheet, Page: sheetPage, Pages: len(layout)})
+		}
+	}
+	return page
+}
+
+// Answer sheets for mock exams taken on paper: printing them, and scoring them from their scans.
+func registerAnswerSheets(admin *echo.Group) {
+	admin.POST("/exams/:id/answer-sheets", func(c echo.Context) error {
+		exam, ok := findExam(c.Param("id"))
+		if !ok {
+			return echo.NewHTTPError(http.StatusNotFound, "the exam is not published")
+		}
+
+		names := []string{}
+		for _, line := range strings.Split(c.FormValue("Students"), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				names = append(names, line)
+			}
+		}
+		count, _ := strconv.Atoi(c.FormValue("Count"))
+
+		sheets, err := newAnswerSheets(exam, names, count, time.Now())
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if err := recordAnswerSheets(sheets); err != nil {
+			return err
+		}
+
+		return c.Render(http.StatusCreated, "answer-sheets-page", newAnswerSheetsPage(exam, sheets))
+	})
+
+	admin.GET("/answer-sheets", func(c echo.Context) error {
+		return c.Render(http.StatusOK, "admin-answer-sheets-page", nil)
+	})
+
+	admin.POST("/answer-sheets/scans", scanAnswerSheet)
+	admin.POST("/answer-sheets/:id/answers", confirmAnswerSheet)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// Test: the sheet and page numbers are decoded from their code as they were encoded, and damaged codes are rejected
+func TestSheetCode(t *testing.T) {
+	roundTrip := func(number uint64, page uint8, flipped uint8) bool {
+		number %= 1 << sheetNumberBits
+		pageNumber := int(page%(1<<sheetPageBits)) + 1
+
+		bits := []bool{}
+		for _, cell := range sheetCode(number, pageNumber) {
+			bits = append(bits, cell.Dark)
+		}
+		decodedNumber, decodedPage, ok := decodeSheetCode(bits)
+		if !ok || decodedNumber != number || decodedPage != pageNumber {
+			return false
+		}
+
+		bits[int(flipped)%len(bits)] = !bits[int(flipped)%len(bits)]
+		_, _, ok = decodeSheetCode(bits)
+		return !ok
+	}
+
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: every option of every bubbled question has a bubble within the grid, away from every other bubble
+func TestLayoutAnswerSheet(t *testing.T) {
+	psychometry := generateFakeData()
+	psychometry.Sections[0].Questions = append(psychometry.Sections[0].Questions, Question{ID: "numeric", Type: NumericEntry})
+
+	// Repeats the sections until they take more than a page
+	for len(layoutAnswerSheet(psychometry)) < 2 {
+		psychometry.Sections = append(psychometry.Sections, psychometry.Sections...)
+	}
+
+	expected := 0
+	for _, section := range psychometry.Sections {
+		for _, question := range section.Questions {
+			expected += len(question.Options)
+		}
+	}
+
+	actual := 0
+	for _, page := range layoutAnswerSheet(psychometry) {
+		seen := map[string]bool{}
+		for _, bubble := range page.Bubbles {
+			actual += 1
+
+			key := fmt.Sprintf("%d-%d-%d", bubble.Section, bubble.Question, bubble.Option)
+			if seen[key] {
+				t.Errorf("bubble %s is repeated", key)
+			}
+			seen[key] = true
+
+			if bubble.X-bubbleRadius < gridLeft || bubble.X+bubbleRadius > gridRight || bubble.Y-bubbleRadius < gridTop || bubble.Y+bubbleRadius > gridBottom {
+				t.Errorf("bubble %s is outside of the grid at (%v, %v)", key, bubble.X, bubble.Y)
+			}
+		}
+
+		for i, a := range page.Bubbles {
+			for _, b := range page.Bubbles[i+1:] {
+				if math.Hypot(a.X-b.X, a.Y-b.Y) < 2*bubbleRadius+1 {
+					t.Errorf("bubbles at (%v, %v) and (%v, %v) are too close", a.X, a.Y, b.X, b.Y)
+				}
+			}
+		}
+	}
+
+	if actual != expected {
+		t.Errorf("expected %d bubbles, got %d", expected, actual)
+	}
+}
+
+// Test: sheets are printed for every student, or blank, with a distinct ID each
+func TestNewAnswerSheets(t *testing.T) {
+	exam := Exam{ID: "fake", Version: 2, Psychometry: generateFakeData()}
+
+	sheets, err := newAnswerSheets(exam, []string{"דנה", "יובל"}, 5, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheets) != 2 || sheets[0].Name != "דנה" || sheets[0].ExamVersion != "fake.v2" || sheets[0].ID == sheets[1].ID {
+		t.Errorf("expected a sheet for every student, got %v", sheets)
+	}
+
+	if sheets, err := newAnswerSheets(exam, nil, 3, time.Now()); err != nil || len(sheets) != 3 || sheets[0].Name != "" {
+		t.Errorf("expected 3 blank sheets, got %v (%v)", sheets, err)
+	}
+	if _, err := newAnswerSheets(exam, nil, maximumAnswerSheets+1, time.Now()); err == nil {
+		t.Error("expected too many sheets to be rejected")
+	}
+}
+
+// Test: an exam is printed as long as its answer sheet has no more pages than the sheet code can number
+func TestNewAnswerSheets_pages(t *testing.T) {
+	exam := func(questions int) Exam {
+		section := Section{Kind: Q, IsCounted: true}
+		for i := range questions {
+			section.Questions = append(section.Questions, Question{ID: fmt.Sprint(i), Options: make([]string, 4)})
+		}
+		return Exam{ID: "long", Version: 1, Psychometry: Psychometry{Sections: []Section{section}}}
+	}
+
+	// The fewest questions that do not fit
+	overflowing := sort.Search(10000, func(questions int) bool {
+		return len(layoutAnswerSheet(exam(questions).Psychometry)) > maximumSheetPages
+	})
+	if overflowing == 10000 || len(layoutAnswerSheet(exam(overflowing-1).Psychometry)) != maximumSheetPages {
+		t.Fatalf("expected an exam of %d questions to fill every page", overflowing-1)
+	}
+
+	if _, err := newAnswerSheets(exam(overflowing-1), nil, 1, time.Now()); err != nil {
+		t.Errorf("expected %d pages to be printed, got %v", maximumSheetPages, err)
+	}
+	if _, err := newAnswerSheets(exam(overflowing), nil, 1, time.Now()); err == nil {
+		t.Errorf("expected %d pages to be rejected", maximumSheetPages+1)
+	}
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// The rich content of a question, which the authoring pages keep as it is rather than edit.
+type questionRichContent struct {
+	PassageID    string
+	Blocks       []Block
+	OptionBlocks [][]Block
+}
+
+// Parses an exam from the form of the authoring page, where every field is keyed by its position (e.g.
+// `Sections[0][Questions][1][Content]`).
+//
+// Positions only order the sections and questions, so gaps between them are ignored. Fields that cannot be parsed are
+// returned as problems, at the position they end up in.
+func parseExamForm(form url.Values) (Psychometry, error) {
+	sectionFields := map[int]url.Values{}
+	questionFields := map[int]map[int]url.Values{}
+	for key, values := range form {
+		path := splitFormKey(key)
+		if path[0] != "Sections" || len(path) < 3 || len(values) == 0 {
+			continue
+		}
+		i, err := strconv.Atoi(path[1])
+		if err != nil {
+			continue
+		}
+
+		if _, ok := sectionFields[i]; !ok {
+			sectionFields[i] = url.Values{}
+			questionFields[i] = map[int]url.Values{}
+		}
+
+		if path[2] != "Questions" {
+			sectionFields[i].Set(path[2], values[0])
+			continue
+		}
+		if len(path) < 5 {
+			continue
+		}
+		j, err := strconv.Atoi(path[3])
+		if err != nil {
+			continue
+		}
+		if _, ok := questionFields[i][j]; !ok {
+			questionFields[i][j] = url.Values{}
+		}
+		questionFields[i][j].Set(path[4], values[0])
+	}
+
+	psychometry := Psychometry{}
+	problems := []error{}
+	for _, i := range sortedKeys(sectionFields) {
+		fields := sectionFields[i]
+		section := Section{
+			Kind:      SectionKind(fields.Get("Kind")),
+			Index:     len(psychometry.Sections),
+			IsCounted: fields.Get("IsCounted") != "",
+		}
+		if passages := fields.Get("Passages"); passages != "" {
+			if err := json.Unmarshal([]byte(passages), &section.Passages); err != nil {
+				problems = append(problems, ExamProblem{section.Index, -1, -1, errors.New("invalid passages")})
+			}
+		}
+
+		for _, j := range sortedKeys(questionFields[i]) {
+			question, errs := parseQuestionFields(questionFields[i][j])
+			for _, err := range errs {
+				problems = append(problems, ExamProblem{section.Index, len(section.Questions), -1, err})
+			}
+			section.Questions = append(section.Questions, question)
+		}
+
+		psychometry.Sections = append(psychometry.Sections, section)
+	}
+
+	return psychometry, errors.Join(problems...)
+}
+
+func parseQuestionFields(fields url.Values) (Question, []error) {
+	question := Question{
+		ID:      strings.TrimSpace(fields.Get("ID")),
+		Content: strings.TrimSpace(fields.Get("Content")),
+		Topic:   Topic(fields.Get("Topic")),
+		Type:    ItemType(fields.Get("Type")),
+		Options: parseOptionLines(fields.Get("Options")),
+	}
+
+	problems := []error{}
+	// Answers are numbered from 1 on the page, like the options are labelled in the booklet
+	switch question.Type {
+	case MultipleChoice:
+		number, err := strconv.Atoi(strings.TrimSpace(fields.Get("CorrectOption")))
+		if err != nil {
+			problems = append(problems, errors.New("missing correct option"))
+		}
+		question.CorrectOption = number - 1
+	case NumericEntry:
+		answer, err := parseNumericEntry(fields.Get("NumericAnswer"))
+		if err != nil || answer == "" {
+			problems = append(problems, errors.New("missing numeric answer"))
+		}
+		question.NumericAnswer, _ = strconv.ParseFloat(answer, 64)
+
+		tolerance, err := parseNumericEntry(fields.Get("Tolerance"))
+		if err != nil {
+			problems = append(problems, errors.New("invalid tolerance"))
+		}
+		question.Tolerance, _ = strconv.ParseFloat(tolerance, 64)
+	case MultipleSelect:
+		for _, part := range strings.Split(fields.Get("CorrectOptions"), ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			number, err := strconv.Atoi(part)
+			if err != nil {
+				problems = append(problems, fmt.Errorf("invalid correct option %q", part))
+				continue
+			}
+			question.CorrectOptions = append(question.CorrectOptions, number-1)
+		}
+	}
+
+	if rich := fields.Get("Rich"); rich != "" {
+		var content questionRichContent
+		if err := json.Unmarshal([]byte(rich), &content); err != nil {
+			problems = append(problems, errors.New("invalid rich content"))
+		}
+		question.PassageID = content.PassageID
+		question.Blocks = content.Blocks
+		question.OptionBlocks = content.OptionBlocks
+	}
+
+	return question, problems
+}
+
+// Every line is an option, except for empty lines at the end.
+func parseOptionLines(text string) []string {
+	options := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		options = append(options, strings.TrimSpace(line))
+	}
+	for len(options) > 0 && options[len(options)-1] == "" {
+		options = options[:len(options)-1]
+	}
+	if len(options) == 0 {
+		return nil
+	}
+	return options
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := []int{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// Saves a draft of an exam, replacing any earlier draft of it.
+func saveExamDraft(id string, psychometry Psychometry) error {
+	if dir := examsPath(); dir != "" {
+		if err := writeExamFile(filepath.Join(dir, "drafts", id+".json"), psychometry); err != nil {
+			return err
+		}
+	}
+
+	examDrafts[id] = psychometry
+	return nil
+}
+
+// Publishes a new version of an exam, replacing its draft, and its earlier versions in the bank. Nothing is published
+// when the exam has any problem, and publishing an exam as it already is returns its latest version.
+func publishExam(id string, psychometry Psychometry) (Exam, error) {
+	if err := validateExam(psychometry, assets); err != nil {
+		return Exam{}, err
+	}
+
+	latest, isPublished := findExam(id)
+	unchanged := isPublished && reflect.DeepEqual(latest.Psychometry, psychometry)
+
+	exam := Exam{ID: id, Version: latest.Version + 1, Psychometry: psychometry}
+	if unchanged {
+		exam = latest
+	} else if err := checkBankIDs(append(latestExams(), exam)); err != nil {
+		return Exam{}, err
+	}
+
+	if dir := examsPath(); dir != "" {
+		if !unchanged {
+			if err := createExamFile(filepath.Join(dir, exam.VersionID()+".json"), psychometry); err != nil {
+				return Exam{}, err
+			}
+		}
+		if err := os.Remove(filepath.Join(dir, "drafts", id+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return Exam{}, err
+		}
+	}
+
+	if !unchanged {
+		exams = append(exams, exam)
+		sort.SliceStable(exams, func(i, j int) bool {
+			return exams[i].ID < exams[j].ID
+		})
+		rebuildBank()
+	}
+	delete(examDrafts, id)
+	return exam, nil
+}
+
+type ExamSummary struct {
+	ID string
+	// The latest published version, or zero when the exam was not published yet.
+	Version  int
+	HasDraft bool
+}
+
+type ExamEditor struct {
+	ID          string
+	Psychometry Psychometry
+	// The latest published version, or zero when the exam was not published yet.
+	Version  int
+	HasDraft bool
+	Problems []ExamProblem
+	// The outcome of saving or publishing, if the exam was just saved or published.
+	Message string
+}
+
+type SectionEditor struct {
+	Section   Section
+	Problems  []string
+	Questions []QuestionEditor
+}
+
+type QuestionEditor struct {
+	Question Question
+	Problems []string
+}
+
+func newExamEditor(id string, psychometry Psychometry, problems error) ExamEditor {
+	latest, _ := findExam(id)
+	_, hasDraft := examDrafts[id]
+	return ExamEditor{
+		ID:          id,
+		Psychometry: psychometry,
+		Version:     latest.Version,
+		HasDraft:    hasDraft,
+		Problems:    examProblems(problems),
+	}
+}
+
+// Fields for a section or a question added while writing, which a new section starts without.
+func (ExamEditor) NewSection() SectionEditor {
+	return SectionEditor{Section: Section{Kind: V, IsCounted: true}}
+}
+
+func (ExamEditor) NewQuestion() QuestionEditor {
+	return QuestionEditor{}
+}
+
+// The problems that are not about any section.
+func (e ExamEditor) ExamProblems() []string {
+	return e.problemsAt(-1, -1)
+}
+
+func (e ExamEditor) Sections() []SectionEditor {
+	sections := []SectionEditor{}
+	for i, section := range e.Psychometry.Sections {
+		editor := SectionEditor{Section: section, Problems: e.problemsAt(i, -1)}
+		for j, question := range section.Questions {
+			editor.Questions = append(editor.Questions, QuestionEditor{Question: question, Problems: e.problemsAt(i, j)})
+		}
+		sections = append(sections, editor)
+	}
+	return sections
+}
+
+func (e ExamEditor) problemsAt(section int, question int) []string {
+	problems := []string{}
+	for _, problem := range e.Problems {
+		if problem.Section != section || problem.Question != question {
+			continue
+		}
+		if problem.Option >= 0 {
+			problems = append(problems, fmt.Sprintf("option %d: %v", problem.Option+1, problem.Err))
+		} else {
+			problems = append(problems, problem.Err.Error())
+		}
+	}
+	return problems
+}
+
+// The kinds a section may be, in the order of the real test.
+func (SectionEditor) Kinds() []SectionKind {
+	return []SectionKind{V, Q, E}
+}
+
+// Passages are not edited in the authoring pages, so they are kept in the form as they are.
+func (e SectionEditor) PassagesJSON() string {
+	if len(e.Section.Passages) == 0 {
+		return ""
+	}
+	content, _ := json.Marshal(e.Section.Passages)
+	return string(content)
+}
+
+type TopicOption struct {
+	Topic    Topic
+	Name     string
+	Selected bool
+}
+
+func (e QuestionEditor) Topics() []TopicOption {
+	topics := []TopicOption{}
+	for topic, name := range topicNames {
+		topics = append(topics, TopicOption{Topic: topic, Name: name, Selected: topic == e.Question.Topic})
+	}
+	sort.Slice(topics, func(i, j int) bool {
+		return topics[i].Topic < topics[j].Topic
+	})
+	return topics
+}
+
+func (e QuestionEditor) OptionLines() string {
+	return strings.Join(e.Question.Options, "\n")
+}
+
+// The correct option, numbered from 1, or 0 when there is none.
+func (e QuestionEditor) CorrectNumber() int {
+	if len(e.Question.Options) == 0 {
+		return 0
+	}
+	return e.Question.CorrectOption + 1
+}
+
+func (e QuestionEditor) CorrectNumbers() string {
+	numbers := []string{}
+	for _, option := range e.Question.CorrectOptions {
+		numbers = append(numbers, strconv.Itoa(option+1))
+	}
+	return strings.Join(numbers, ",")
+}
+
+func (e QuestionEditor) NumericAnswer() string {
+	if !e.Question.IsNumericEntry() {
+		return ""
+	}
+	return strconv.FormatFloat(e.Question.NumericAnswer, 'g', -1, 64)
+}
+
+func (e QuestionEditor) Tolerance() string {
+	if e.Question.Tolerance == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(e.Question.Tolerance, 'g', -1, 64)
+}
+
+// Rich content is not edited in the authoring pages, so it is kept in the form as it is.
+func (e QuestionEditor) RichJSON() string {
+	q := e.Question
+	if q.PassageID == "" && len(q.Blocks) == 0 && len(q.OptionBlocks) == 0 {
+		return ""
+	}
+	content, _ := json.Marshal(questionRichContent{PassageID: q.PassageID, Blocks: q.Blocks, OptionBlocks: q.OptionBlocks})
+	return string(content)
+}
+
+// Pages for writing exams: an exam is edited as a draft, which only reaches the bank once it is published.
+func registerExamAuthoring(admin *echo.Group) {
+	admin.GET("/exams", func(c echo.Context) error {
+		summaries := map[string]*ExamSummary{}
+		for _, exam := range latestExams() {
+			summaries[exam.ID] = &ExamSummary{ID: exam.ID, Version: exam.Version}
+		}
+		for id := range examDrafts {
+			if _, ok := summaries[id]; !ok {
+				summaries[id] = &ExamSummary{ID: id}
+			}
+			summaries[id].HasDraft = true
+		}
+
+		list := []ExamSummary{}
+		for _, summary := range summaries {
+			list = append(list, *summary)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].ID < list[j].ID
+		})
+
+		return c.Render(http.StatusOK, "admin-exams-page", list)
+	})
+
+	admin.POST("/exams", func(c echo.Context) error {
+		id := strings.TrimSpace(c.FormValue("id"))
+		if !examIDRegexp.MatchString(id) {
+			return echo.NewHTTPError(http.StatusBadRequest, "exam IDs may only have English letters, digits, - and _")
+		}
+		_, isPublished := findExam(id)
+		if _, hasDraft := examDrafts[id]; hasDraft || isPublished {
+			return echo.NewHTTPError(http.StatusConflict, "an exam with this ID already exists")
+		}
+
+		if err := saveExamDraft(id, Psychometry{}); err != nil {
+			return err
+		}
+		return c.Redirect(http.StatusSeeOther, "/admin/exams/"+id)
+	})
+
+	admin.GET("/exams/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		psychometry, ok := examDrafts[id]
+		if !ok {
+			exam, isPublished := findExam(id)
+			if !isPublished {
+				return echo.NewHTTPError(http.StatusNotFound, "unknown exam")
+			}
+			psychometry = exam.Psychometry
+		}
+
+		return c.Render(http.StatusOK, "admin-exam-page", newExamEditor(id, psychometry, validateExam(psychometry, assets)))
+	})
+
+	// Checks the exam as it is being written, for the preview and the problems shown next to every field
+	admin.POST("/exams/:id/preview", func(c echo.Context) error {
+		id, form, err := parseAuthoringRequest(c)
+		if err != nil {
+			return err
+		}
+
+		psychometry, err := parseExamForm(form)
+		editor := newExamEditor(id, psychometry, errors.Join(err, validateExam(psychometry, assets)))
+		return c.Render(http.StatusOK, "admin-exam-checked", editor)
+	})
+
+	admin.POST("/exams/:id", func(c echo.Context) error {
+		id, form, err := parseAuthoringRequest(c)
+		if err != nil {
+			return err
+		}
+
+		psychometry, parseErr := parseExamForm(form)
+		problems := errors.Join(parseErr, validateExam(psychometry, assets))
+
+		message := ""
+		switch form.Get("action") {
+		case "draft":
+			if err := saveExamDraft(id, psychometry); err != nil {
+				return err
+			}
+			message = "הטיוטה נשמרה."
+		case "publish":
+			if parseErr != nil {
+				message = "יש לתקן את הבעיות במבחן לפני פרסומו."
+			} else if exam, err := publishExam(id, psychometry); err != nil {
+				problems = err
+				message = "יש לתקן את הבעיות במבחן לפני פרסומו."
+			} else {
+				message = fmt.Sprintf("גרסה %d של המבחן פורסמה, ושאלותיה נוספו למאגר.", exam.Version)
+			}
+		default:
+			return echo.NewHTTPError(http.StatusBadRequest, "unknown action")
+		}
+
+		editor := newExamEditor(id, psychometry, problems)
+		editor.Message = message
+		return c.Render(http.StatusOK, "admin-exam-checked", editor)
+	})
+}
+
+func parseAuthoringRequest(c echo.Context) (string, url.Values, error) {
+	id := c.Param("id")
+	_, isPublished := findExam(id)
+	if _, hasDraft := examDrafts[id]; !hasDraft && !isPublished {
+		return "", nil, echo.NewHTTPError(http.StatusNotFound, "unknown exam")
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return "", nil, err
+	}
+	return id, form, nil
+}
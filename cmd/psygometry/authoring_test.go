@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Fills the form of the authoring page the way the page does, from the fields it shows.
+func examForm(editor ExamEditor) url.Values {
+	form := url.Values{}
+	for i, section := range editor.Sections() {
+		prefix := fmt.Sprintf("Sections[%d]", i)
+		form.Set(prefix+"[Kind]", string(section.Section.Kind))
+		if section.Section.IsCounted {
+			form.Set(prefix+"[IsCounted]", "on")
+		}
+		form.Set(prefix+"[Passages]", section.PassagesJSON())
+
+		for j, question := range section.Questions {
+			prefix := fmt.Sprintf("Sections[%d][Questions][%d]", i, j)
+			form.Set(prefix+"[ID]", question.Question.ID)
+			form.Set(prefix+"[Type]", string(question.Question.Type))
+			form.Set(prefix+"[Topic]", string(question.Question.Topic))
+			form.Set(prefix+"[Content]", question.Question.Content)
+			form.Set(prefix+"[Options]", question.OptionLines())
+			form.Set(prefix+"[CorrectOption]", fmt.Sprint(question.CorrectNumber()))
+			form.Set(prefix+"[CorrectOptions]", question.CorrectNumbers())
+			form.Set(prefix+"[NumericAnswer]", question.NumericAnswer())
+			form.Set(prefix+"[Tolerance]", question.Tolerance())
+			form.Set(prefix+"[Rich]", question.RichJSON())
+		}
+	}
+	return form
+}
+
+// Test: an exam is parsed back from its form as it was, including the content the form does not edit
+func TestParseExamForm_roundTrip(t *testing.T) {
+	psychometry := generateFakeData()
+	psychometry.WritingSection = WritingPrompt{}
+	psychometry.Sections[0].Questions = append(psychometry.Sections[0].Questions,
+		Question{ID: "numeric", Content: "$x$", Type: NumericEntry, NumericAnswer: 2.5, Tolerance: 0.1},
+		Question{ID: "multi-select", Content: "y", Type: MultipleSelect, Options: []string{"a", "b", "c"}, CorrectOptions: []int{0, 2}},
+	)
+
+	parsed, err := parseExamForm(examForm(newExamEditor("fake", psychometry, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, psychometry) {
+		t.Errorf("expected %v, got %v", psychometry, parsed)
+	}
+}
+
+// Test: sections and questions are ordered by their positions, and fields that cannot be parsed are located
+func TestParseExamForm_positions(t *testing.T) {
+	form := url.Values{
+		"Sections[3][Kind]":                        {"E"},
+		"Sections[1][Kind]":                        {"Q"},
+		"Sections[1][Questions][7][ID]":            {"second"},
+		"Sections[1][Questions][7][Options]":       {"a\nb"},
+		"Sections[1][Questions][2][ID]":            {"first"},
+		"Sections[1][Questions][2][Options]":       {"a\nb\n\n"},
+		"Sections[1][Questions][2][CorrectOption]": {"2"},
+	}
+
+	psychometry, err := parseExamForm(form)
+	if len(psychometry.Sections) != 2 || psychometry.Sections[0].Kind != Q || psychometry.Sections[1].Kind != E {
+		t.Fatalf("expected the sections in order, got %v", psychometry.Sections)
+	}
+
+	questions := psychometry.Sections[0].Questions
+	if len(questions) != 2 || questions[0].ID != "first" || questions[1].ID != "second" {
+		t.Fatalf("expected the questions in order, got %v", questions)
+	}
+	if len(questions[0].Options) != 2 || questions[0].CorrectOption != 1 {
+		t.Errorf("expected 2 options with the second correct, got %v", questions[0])
+	}
+
+	problems := examProblems(err)
+	if len(problems) != 1 || problems[0].Section != 0 || problems[0].Question != 1 {
+		t.Errorf("expected the missing correct option of the second question, got %v", problems)
+	}
+}
+
+// Test: published exams are written to the bank and replace their drafts, while exams with problems are not published
+func TestPublishExam(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("EXAMS_PATH", dir)
+	defer func(original []Exam, drafts map[string]Psychometry) {
+		exams, examDrafts = original, drafts
+		rebuildBank()
+	}(exams, examDrafts)
+	exams, examDrafts = []Exam{}, map[string]Psychometry{}
+
+	psychometry := generateFakeData()
+	psychometry.Sections[0].Questions[0].ID = "published"
+	if err := saveExamDraft("fake", psychometry); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := psychometry
+	invalid.Sections = nil
+	if _, err := publishExam("fake", invalid); err == nil {
+		t.Fatal("expected an exam without sections not to be published")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "fake.v1.json")); err == nil {
+		t.Fatal("expected nothing to be written")
+	}
+
+	if _, err := publishExam("fake", psychometry); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := examDrafts["fake"]; ok {
+		t.Error("expected the draft to be removed")
+	}
+	if _, _, ok := findQuestion("published"); !ok {
+		t.Error("expected the published questions to be drawn for practice")
+	}
+
+	loaded, err := loadExams(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 || loaded[0].ID != "fake" || !reflect.DeepEqual(loaded, exams) {
+		t.Errorf("expected the published exam to be loaded as it is in the bank, got %v", loaded)
+	}
+
+	drafts, err := loadExamDrafts(dir)
+	if err != nil || len(drafts) != 0 {
+		t.Errorf("expected no drafts, got %v (%v)", drafts, err)
+	}
+
+	if _, err := publishExam("copy", psychometry); err == nil {
+		t.Error("expected questions already in another exam to be rejected")
+	}
+
+	if exam, err := publishExam("fake", psychometry); err != nil || exam.Version != 1 {
+		t.Errorf("expected publishing the same exam to keep its version, got %d (%v)", exam.Version, err)
+	}
+
+	fixed := generateFakeData()
+	fixed.Sections[0].Questions[0].Content = "fixed"
+	if exam, err := publishExam("fake", fixed); err != nil || exam.Version != 2 {
+		t.Fatalf("expected a second version, got %d (%v)", exam.Version, err)
+	}
+	if loaded, err := loadExams(dir); err != nil || len(loaded) != 2 || !reflect.DeepEqual(loaded[0].Psychometry, psychometry) {
+		t.Errorf("expected the first version to be kept as it was, got %v (%v)", loaded, err)
+	}
+	if err := createExamFile(filepath.Join(dir, "fake.v1.json"), fixed); err == nil {
+		t.Error("expected a published version not to be replaced")
+	}
+}
@@ -0,0 +1,669 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-fonts/liberation/liberationsansbold"
+	"github.com/go-fonts/liberation/liberationsansregular"
+	"github.com/go-pdf/fpdf"
+	"github.com/labstack/echo/v4"
+)
+
+// The font printed documents are set in, which covers both Hebrew and English.
+const pdfFont = "LiberationSans"
+
+const (
+	pdfMargin       = 20
+	pdfBodySize     = 11
+	pdfHeadingSize  = 16
+	pdfTitleSize    = 20
+	pdfOptionIndent = 8
+	// Spacing of the lines that the essay is written on, as on the lined pages of the real exam.
+	essayLineSpacing = 9
+	essayPages       = 2
+)
+
+var sectionKindNames = map[SectionKind]string{
+	V: "חשיבה מילולית",
+	Q: "חשיבה כמותית",
+	E: "אנגלית",
+}
+
+// Brackets that face the other way when their text is laid out right to left.
+var mirroredRunes = map[rune]rune{'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{', '<': '>', '>': '<'}
+
+func isRightToLeft(r rune) bool {
+	return unicode.In(r, unicode.Hebrew, unicode.Arabic)
+}
+
+// Reorders a single line of text from the order it is read in to the order it is drawn in, from left to right, since
+// PDF text has no direction of its own.
+//
+// This is a simplified form of the Unicode bidirectional algorithm: letters and digits are laid out in the direction
+// of their script, and spaces and punctuation in the direction of the letters on both sides of them, or of the
+// paragraph when those differ. Numbers are kept together with Latin text, so that math such as "2 + 3" reads the same
+// in Hebrew questions.
+func visualOrder(line string, rtl bool) string {
+	runes := []rune(line)
+	directions := make([]int, len(runes))
+	paragraph := 1
+	if rtl {
+		paragraph = -1
+	}
+
+	// Letters are resolved first (1 for left to right, -1 for right to left), and everything else after them
+	for i, r := range runes {
+		switch {
+		case isRightToLeft(r):
+			directions[i] = -1
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			directions[i] = 1
+		}
+	}
+	for i := 0; i < len(runes); i++ {
+		if directions[i] != 0 {
+			continue
+		}
+
+		end := i
+		for end < len(runes) && directions[end] == 0 {
+			end += 1
+		}
+		before, after := paragraph, paragraph
+		if i > 0 {
+			before = directions[i-1]
+		}
+		if end < len(runes) {
+			after = directions[end]
+		}
+
+		direction := paragraph
+		if before == after {
+			direction = before
+		}
+		for j := i; j < end; j++ {
+			directions[j] = direction
+		}
+		i = end - 1
+	}
+
+	// Runs against the direction of the paragraph are drawn in reverse, and so is the order of the runs themselves in
+	// right to left paragraphs
+	runs := [][]rune{}
+	for i := 0; i < len(runes); {
+		end := i
+		for end < len(runes) && directions[end] == directions[i] {
+			end += 1
+		}
+
+		run := append([]rune{}, runes[i:end]...)
+		if directions[i] < 0 {
+			for a, b := 0, len(run)-1; a < b; a, b = a+1, b-1 {
+				run[a], run[b] = run[b], run[a]
+			}
+			for j, r := range run {
+				if mirrored, ok := mirroredRunes[r]; ok {
+					run[j] = mirrored
+				}
+			}
+		}
+		runs = append(runs, run)
+		i = end
+	}
+	if rtl {
+		for a, b := 0, len(runs)-1; a < b; a, b = a+1, b-1 {
+			runs[a], runs[b] = runs[b], runs[a]
+		}
+	}
+
+	var result strings.Builder
+	for _, run := range runs {
+		result.WriteString(string(run))
+	}
+	return result.String()
+}
+
+// An element of rendered MathML, for writing the math out as plain text.
+type mathNode struct {
+	name     string
+	text     string
+	children []mathNode
+}
+
+func parseMathNode(decoder *xml.Decoder, name string) (mathNode, error) {
+	node := mathNode{name: name}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return node, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			child, err := parseMathNode(decoder, token.Name.Local)
+			if err != nil {
+				return node, err
+			}
+			node.children = append(node.children, child)
+		case xml.CharData:
+			node.text += string(token)
+		case xml.EndElement:
+			return node, nil
+		}
+	}
+}
+
+// Writes math out as a single line of text, such as "(x+1)/2" for a fraction.
+func (n mathNode) linear() string {
+	// Parts that are longer than a single character are grouped, so that they read as one
+	group := func(node mathNode) string {
+		text := node.linear()
+		if utf8.RuneCountInString(text) > 1 {
+			return "(" + text + ")"
+		}
+		return text
+	}
+
+	child := func(i int) mathNode {
+		if i < len(n.children) {
+			return n.children[i]
+		}
+		return mathNode{}
+	}
+
+	switch n.name {
+	case "mn", "mi", "mo", "mtext":
+		return n.text
+	case "mfrac":
+		return group(child(0)) + "/" + group(child(1))
+	case "msup":
+		return child(0).linear() + "^" + group(child(1))
+	case "msub":
+		return child(0).linear() + "_" + group(child(1))
+	case "msubsup":
+		return child(0).linear() + "_" + group(child(1)) + "^" + group(child(2))
+	case "msqrt":
+		return "√" + group(mathNode{children: n.children})
+	case "mroot":
+		return child(1).linear() + "√" + group(child(0))
+	case "mover":
+		return child(0).linear()
+	default:
+		var result strings.Builder
+		for _, c := range n.children {
+			result.WriteString(c.linear())
+		}
+		return result.String()
+	}
+}
+
+// Writes math markup out as plain text, or returns its source when it cannot be rendered.
+func linearMath(source string) string {
+	rendered, err := renderMath(source, false)
+	if err != nil {
+		return source
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(string(rendered)))
+	root, err := parseMathNode(decoder, "")
+	if err != nil && !errors.Is(err, io.EOF) {
+		return source
+	}
+	return root.linear()
+}
+
+// Writes text with inline math out as plain text.
+func plainText(text string) string {
+	segments, err := splitInlineMath(text)
+	if err != nil {
+		return text
+	}
+
+	var result strings.Builder
+	for i, segment := range segments {
+		if i%2 == 1 {
+			segment = linearMath(segment)
+		}
+		result.WriteString(segment)
+	}
+	return result.String()
+}
+
+// A PDF document being printed, with text laid out in the direction of its language.
+type pdfDocument struct {
+	pdf *fpdf.Fpdf
+	rtl bool
+}
+
+func newPDFDocument(title string) *pdfDocument {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(title, true)
+	pdf.AddUTF8FontFromBytes(pdfFont, "", liberationsansregular.TTF)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", liberationsansbold.TTF)
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin / 2)
+		pdf.SetFont(pdfFont, "", 9)
+		pdf.CellFormat(0, 5, strconv.Itoa(pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	return &pdfDocument{pdf: pdf, rtl: true}
+}
+
+func (d *pdfDocument) align() string {
+	if d.rtl {
+		return "R"
+	}
+	return "L"
+}
+
+func lineHeight(size float64) float64 {
+	return size * 0.5
+}
+
+func (d *pdfDocument) contentWidth() float64 {
+	width, _ := d.pdf.GetPageSize()
+	left, _, right, _ := d.pdf.GetMargins()
+	return width - left - right
+}
+
+// Splits text into the lines it is printed in, in the order they are read.
+func (d *pdfDocument) lines(text string, size float64, style string) []string {
+	d.pdf.SetFont(pdfFont, style, size)
+
+	lines := []string{}
+	for _, paragraph := range strings.Split(text, "\n") {
+		lines = append(lines, d.pdf.SplitText(paragraph, d.contentWidth())...)
+	}
+	return lines
+}
+
+func (d *pdfDocument) paragraph(text string, size float64, style string) {
+	for _, line := range d.lines(text, size, style) {
+		d.pdf.CellFormat(0, lineHeight(size), visualOrder(line, d.rtl), "", 1, d.align(), false, 0, "")
+	}
+}
+
+// Prints the contents indented from the side that text starts on.
+func (d *pdfDocument) indented(indent float64, print func()) {
+	left, top, right, _ := d.pdf.GetMargins()
+	if d.rtl {
+		d.pdf.SetMargins(left, top, right+indent)
+	} else {
+		d.pdf.SetMargins(left+indent, top, right)
+		d.pdf.SetX(left + indent)
+	}
+
+	print()
+
+	d.pdf.SetMargins(left, top, right)
+	d.pdf.SetX(left)
+}
+
+// Starts a new page when less than the given height is left on the current one.
+func (d *pdfDocument) keepTogether(height float64) {
+	_, pageHeight := d.pdf.GetPageSize()
+	_, _, _, bottom := d.pdf.GetMargins()
+	if d.pdf.GetY()+height > pageHeight-bottom {
+		d.pdf.AddPage()
+	}
+}
+
+func (d *pdfDocument) heading(text string, size float64) {
+	rtl := d.rtl
+	d.rtl = true
+	d.paragraph(text, size, "B")
+	d.rtl = rtl
+	d.pdf.Ln(lineHeight(size) / 2)
+}
+
+// Prints rich content. Images are printed when they are PNG or JPEG assets, and otherwise described by their
+// alternative text, as PDFs cannot hold the SVG figures that pages show.
+func (d *pdfDocument) blocks(blocks []Block) {
+	for _, block := range blocks {
+		switch block.Kind {
+		case TextBlock:
+			d.paragraph(plainText(block.Text), pdfBodySize, "")
+		case MathBlock:
+			d.pdf.SetFont(pdfFont, "", pdfBodySize)
+			d.pdf.CellFormat(0, lineHeight(pdfBodySize), linearMath(block.Text), "", 1, "C", false, 0, "")
+		case TableBlock:
+			d.table(block.Rows)
+		case ImageBlock:
+			if !d.image(block.Asset) {
+				d.paragraph(fmt.Sprintf("[%s]", block.Alt), pdfBodySize, "")
+			}
+		}
+	}
+}
+
+func (d *pdfDocument) table(rows [][]string) {
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return
+	}
+
+	width := d.contentWidth() / float64(len(rows[0]))
+	for i, row := range rows {
+		style := ""
+		if i == 0 {
+			style = "B"
+		}
+		d.pdf.SetFont(pdfFont, style, pdfBodySize)
+
+		// Right to left tables start from their rightmost column
+		cells := append([]string{}, row...)
+		if d.rtl {
+			for a, b := 0, len(cells)-1; a < b; a, b = a+1, b-1 {
+				cells[a], cells[b] = cells[b], cells[a]
+			}
+		}
+		for j, cell := range cells {
+			ln := 0
+			if j == len(cells)-1 {
+				ln = 1
+			}
+			d.pdf.CellFormat(width, lineHeight(pdfBodySize)+2, visualOrder(plainText(cell), d.rtl), "1", ln, "C", false, 0, "")
+		}
+	}
+	d.pdf.Ln(2)
+}
+
+func (d *pdfDocument) image(asset string) bool {
+	kinds := map[string]string{".png": "PNG", ".jpg": "JPG", ".jpeg": "JPG"}
+	kind, ok := kinds[strings.ToLower(path.Ext(asset))]
+	if !ok {
+		return false
+	}
+	content, err := fs.ReadFile(assets, asset)
+	if err != nil {
+		return false
+	}
+
+	options := fpdf.ImageOptions{ImageType: kind, ReadDpi: true}
+	info := d.pdf.RegisterImageOptionsReader(asset, options, bytes.NewReader(content))
+	if info == nil || d.pdf.Err() {
+		d.pdf.ClearError()
+		return false
+	}
+
+	width := min(info.Width(), d.contentWidth())
+	height := width * info.Height() / info.Width()
+	d.keepTogether(height)
+	x, _ := d.pdf.GetXY()
+	if d.rtl {
+		x = x + d.contentWidth() - width
+	}
+	d.pdf.ImageOptions(asset, x, d.pdf.GetY(), width, height, true, options, 0, "")
+	return true
+}
+
+// Lines the rest of the page for writing on.
+func (d *pdfDocument) writingLines() {
+	_, pageHeight := d.pdf.GetPageSize()
+	left, _, right, bottom := d.pdf.GetMargins()
+	width, _ := d.pdf.GetPageSize()
+
+	d.pdf.SetDrawColor(160, 160, 160)
+	for y := d.pdf.GetY() + essayLineSpacing; y <= pageHeight-bottom; y += essayLineSpacing {
+		d.pdf.Line(left, y, width-right, y)
+	}
+	d.pdf.SetDrawColor(0, 0, 0)
+}
+
+func (d *pdfDocument) output(w io.Writer) error {
+	return d.pdf.Output(w)
+}
+
+// Prints the exam as a booklet to be handed out: a title, every section on pages of its own with its passages before
+// its questions, and the writing task followed by lined pages for the essay.
+func writeBooklet(w io.Writer, exam Exam) error {
+	d := newPDFDocument(exam.VersionID())
+	d.pdf.AddPage()
+	d.heading(fmt.Sprintf("בחינה פסיכומטרית — %s", exam.VersionID()), pdfTitleSize)
+	d.paragraph("לכל שאלה יש לבחור את התשובה הנכונה ולסמן את מספרה בגיליון התשובות.", pdfBodySize, "")
+
+	for i, section := range exam.Psychometry.Sections {
+		if i > 0 {
+			d.pdf.AddPage()
+		}
+		d.pdf.Ln(lineHeight(pdfBodySize))
+		d.heading(fmt.Sprintf("פרק %d — %s", i+1, sectionKindNames[section.Kind]), pdfHeadingSize)
+		d.paragraph(fmt.Sprintf("בפרק זה %d שאלות.", len(section.Questions)), pdfBodySize, "")
+		d.pdf.Ln(lineHeight(pdfBodySize))
+
+		d.rtl = section.Kind != E
+		for _, passage := range section.Passages {
+			d.passage(passage)
+		}
+		for j, question := range section.Questions {
+			d.question(j+1, question)
+		}
+		d.rtl = true
+	}
+
+	prompt := exam.WritingPrompt()
+	d.pdf.AddPage()
+	d.heading("כתיבה", pdfHeadingSize)
+	if prompt.Background != "" {
+		d.paragraph(prompt.Background, pdfBodySize, "")
+		d.pdf.Ln(lineHeight(pdfBodySize))
+	}
+	d.paragraph(prompt.Task, pdfBodySize, "B")
+	d.paragraph(fmt.Sprintf("יש לכתוב בין %d ל-%d שורות.", minimumLines, maximumLines), pdfBodySize, "")
+	d.writingLines()
+	for page := 1; page < essayPages; page++ {
+		d.pdf.AddPage()
+		d.writingLines()
+	}
+
+	return d.output(w)
+}
+
+func (d *pdfDocument) passage(passage Passage) {
+	if passage.Title != "" {
+		d.paragraph(passage.Title, pdfBodySize, "B")
+	}
+
+	// Every fifth line is numbered in the margin, for questions that refer to lines by their numbers
+	for i, line := range passage.Lines {
+		if (i+1)%5 == 0 {
+			y := d.pdf.GetY()
+			x := float64(pdfMargin / 2)
+			if !d.rtl {
+				width, _ := d.pdf.GetPageSize()
+				x = width - pdfMargin
+			}
+			d.pdf.SetFont(pdfFont, "", 8)
+			d.pdf.Text(x, y+lineHeight(pdfBodySize)*0.75, strconv.Itoa(i+1))
+		}
+		d.paragraph(plainText(line), pdfBodySize, "")
+	}
+	d.pdf.Ln(lineHeight(pdfBodySize))
+}
+
+func (d *pdfDocument) question(number int, question Question) {
+	content := fmt.Sprintf("%d. %s", number, plainText(question.Content))
+	switch {
+	case question.IsMultipleSelect():
+		content += " (יש לבחור את כל התשובות הנכונות)"
+	case question.IsNumericEntry():
+		content += " (התשובה היא מספר)"
+	}
+
+	// Questions are kept on a single page along with their options, as long as they fit in one
+	height := float64(len(d.lines(content, pdfBodySize, "B"))+len(question.Options)) * lineHeight(pdfBodySize)
+	d.keepTogether(height)
+
+	d.paragraph(content, pdfBodySize, "B")
+	d.blocks(question.Blocks)
+
+	d.indented(pdfOptionIndent, func() {
+		for k, option := range question.Options {
+			d.paragraph(fmt.Sprintf("(%d) %s", k+1, plainText(option)), pdfBodySize, "")
+			if k < len(question.OptionBlocks) {
+				d.blocks(question.OptionBlocks[k])
+			}
+		}
+		if question.IsNumericEntry() {
+			d.paragraph("תשובה: ____________", pdfBodySize, "")
+		}
+	})
+	d.pdf.Ln(lineHeight(pdfBodySize))
+}
+
+// The correct answer of a question as it is marked on paper: option numbers from 1, or the number to write.
+func answerKeyEntry(question Question) string {
+	switch question.Type {
+	case NumericEntry:
+		answer := strconv.FormatFloat(question.NumericAnswer, 'g', -1, 64)
+		if question.Tolerance > 0 {
+			answer += " ± " + strconv.FormatFloat(question.Tolerance, 'g', -1, 64)
+		}
+		return answer
+	case MultipleSelect:
+		numbers := []string{}
+		for _, option := range question.CorrectOptions {
+			numbers = append(numbers, strconv.Itoa(option+1))
+		}
+		return strings.Join(numbers, ", ")
+	default:
+		return strconv.Itoa(question.CorrectOption + 1)
+	}
+}
+
+// The number of answers printed in each row of the answer key.
+const answerKeyColumns = 5
+
+// Prints the correct answer of every question in the exam, by section.
+func writeAnswerKey(w io.Writer, exam Exam) error {
+	d := newPDFDocument(exam.VersionID() + " answer key")
+	d.pdf.AddPage()
+	d.heading(fmt.Sprintf("מפתח תשובות — %s", exam.VersionID()), pdfTitleSize)
+
+	width := d.contentWidth() / answerKeyColumns
+	height := lineHeight(pdfBodySize) + 2
+	for i, section := range exam.Psychometry.Sections {
+		rows := (len(section.Questions) + answerKeyColumns - 1) / answerKeyColumns
+		d.keepTogether(lineHeight(pdfHeadingSize)*2 + float64(rows)*height)
+		d.heading(fmt.Sprintf("פרק %d — %s", i+1, sectionKindNames[section.Kind]), pdfHeadingSize)
+
+		d.pdf.SetFont(pdfFont, "", pdfBodySize)
+		for j, question := range section.Questions {
+			// Answers are laid out from right to left, like the booklet
+			column := j % answerKeyColumns
+			left, _, _, _ := d.pdf.GetMargins()
+			d.pdf.SetX(left + float64(answerKeyColumns-1-column)*width)
+
+			cell := visualOrder(fmt.Sprintf("%d: %s", j+1, answerKeyEntry(question)), false)
+			ln := 0
+			if column == answerKeyColumns-1 || j == len(section.Questions)-1 {
+				ln = 1
+			}
+			d.pdf.CellFormat(width, height, cell, "1", ln, "C", false, 0, "")
+		}
+		d.pdf.Ln(lineHeight(pdfBodySize))
+	}
+
+	return d.output(w)
+}
+
+// Finds the exam to print, which is its latest version unless another is asked for.
+func findPrintedExam(id string, version int) (Exam, bool) {
+	if version == 0 {
+		return findExam(id)
+	}
+	return findExamVersion(Exam{ID: id, Version: version}.VersionID())
+}
+
+func registerBooklets(admin *echo.Group) {
+	documents := map[string]func(io.Writer, Exam) error{
+		"booklet":    writeBooklet,
+		"answer-key": writeAnswerKey,
+	}
+
+	for name, write := range documents {
+		admin.GET("/exams/:id/"+name+".pdf", func(c echo.Context) error {
+			version, _ := strconv.Atoi(c.QueryParam("version"))
+			exam, ok := findPrintedExam(c.Param("id"), version)
+			if !ok {
+				return echo.NewHTTPError(http.StatusNotFound, "the exam is not published")
+			}
+
+			c.Response().Header().Set(echo.HeaderContentType, "application/pdf")
+			c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`inline; filename="%s-%s.pdf"`, exam.VersionID(), name))
+			c.Response().WriteHeader(http.StatusOK)
+			return write(c.Response(), exam)
+		})
+	}
+}
+
+func exportPDFCommand(args []string) error {
+	flags := flag.NewFlagSet("export-pdf", flag.ContinueOnError)
+	id := flags.String("exam", "", "ID of the exam to print")
+	version := flags.Int("version", 0, "version of the exam to print, instead of its latest version")
+	bookletOutput := flags.String("booklet", "", "path that the booklet is written to")
+	keyOutput := flags.String("key", "", "path that the answer key is written to")
+	dir := flags.String("exams", examsPath(), "path of the directory of exam files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *id == "" {
+		return errors.New("missing exam ID (pass -exam)")
+	}
+	if *bookletOutput == "" && *keyOutput == "" {
+		return errors.New("nothing to write (pass -booklet, -key, or both)")
+	}
+	if *dir == "" {
+		return errors.New("missing exams path (set EXAMS_PATH or pass -exams)")
+	}
+
+	var err error
+	exams, err = loadExams(*dir)
+	if err != nil {
+		return err
+	}
+	exam, ok := findPrintedExam(*id, *version)
+	if !ok {
+		return fmt.Errorf("unknown exam %q", *id)
+	}
+
+	documents := []struct {
+		output string
+		write  func(io.Writer, Exam) error
+	}{{*bookletOutput, writeBooklet}, {*keyOutput, writeAnswerKey}}
+	for _, document := range documents {
+		output, write := document.output, document.write
+		if output == "" {
+			continue
+		}
+
+		file, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		if err := write(file, exam); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+		fmt.Println("wrote", output)
+	}
+	return nil
+}
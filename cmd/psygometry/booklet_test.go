@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode"
+)
+
+// Test: Hebrew is drawn in reverse with its brackets mirrored, while numbers, math and English keep their order
+func TestVisualOrder(t *testing.T) {
+	cases := []struct {
+		line     string
+		rtl      bool
+		expected string
+	}{
+		{"שלום", true, "םולש"},
+		{"(1) שלום עולם", true, "םלוע םולש (1)"},
+		{"כמה הם 2 + 3?", true, "?2 + 3 םה המכ"},
+		{"the word שלום means peace", false, "the word םולש means peace"},
+	}
+
+	for _, c := range cases {
+		if actual := visualOrder(c.line, c.rtl); actual != c.expected {
+			t.Errorf("%q: expected %q, got %q", c.line, c.expected, actual)
+		}
+	}
+
+	unchanged := func(line string) bool {
+		line = strings.Map(func(r rune) rune {
+			if isRightToLeft(r) || !unicode.IsPrint(r) {
+				return -1
+			}
+			return r
+		}, line)
+		return visualOrder(line, false) == line
+	}
+	if err := quick.Check(unchanged, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: math is written out on a single line, grouping the parts of fractions, powers and roots
+func TestPlainText(t *testing.T) {
+	cases := map[string]string{
+		`כמה הם $\frac{x+1}{2}$?`:  "כמה הם (x+1)/2?",
+		`$x^{2} - 1 \le \sqrt{5}$`: "x^2−1≤√5",
+		`$\unknown$`:               `\unknown`,
+	}
+
+	for text, expected := range cases {
+		if actual := plainText(text); actual != expected {
+			t.Errorf("%q: expected %q, got %q", text, expected, actual)
+		}
+	}
+}
+
+var pdfPageRegexp = regexp.MustCompile(`/Type /Page\b[^s]`)
+
+// Test: every section starts a page of the booklet, which ends with the lined pages of the essay, and every type of
+// question has an entry in the answer key
+func TestWriteBooklet(t *testing.T) {
+	psychometry := generateFakeData()
+	psychometry.Sections[0].Questions = append(psychometry.Sections[0].Questions,
+		Question{ID: "numeric", Content: "$x$", Type: NumericEntry, NumericAnswer: 2.5, Tolerance: 0.1},
+		Question{ID: "multi-select", Content: "y", Type: MultipleSelect, Options: []string{"a", "b", "c"}, CorrectOptions: []int{0, 2},
+			Blocks: []Block{{Kind: TableBlock, Rows: [][]string{{"א", "ב"}, {"1", "2"}}}, {Kind: ImageBlock, Asset: "figure.svg", Alt: "איור"}}},
+	)
+	exam := Exam{ID: "fake", Version: 1, Psychometry: psychometry}
+
+	booklet := &bytes.Buffer{}
+	if err := writeBooklet(booklet, exam); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(booklet.Bytes(), []byte("%PDF-")) {
+		t.Fatal("expected a PDF")
+	}
+	if pages := len(pdfPageRegexp.FindAll(booklet.Bytes(), -1)); pages < len(psychometry.Sections)+essayPages {
+		t.Errorf("expected a page for every section and %d for the essay, got %d pages", essayPages, pages)
+	}
+
+	questions := psychometry.Sections[0].Questions
+	if entry := answerKeyEntry(questions[0]); entry != strconv.Itoa(questions[0].CorrectOption+1) {
+		t.Errorf("expected the number of the correct option, got %q", entry)
+	}
+	if entry := answerKeyEntry(questions[len(questions)-2]); entry != "2.5 ± 0.1" {
+		t.Errorf("expected the numeric answer with its tolerance, got %q", entry)
+	}
+	if entry := answerKeyEntry(questions[len(questions)-1]); entry != "1, 3" {
+		t.Errorf("expected the correct option numbers, got %q", entry)
+	}
+
+	key := &bytes.Buffer{}
+	if err := writeAnswerKey(key, exam); err != nil || !bytes.HasPrefix(key.Bytes(), []byte("%PDF-")) {
+		t.Errorf("expected an answer key PDF (%v)", err)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth   = 600
+	chartHeight  = 300
+	chartPadding = 40
+)
+
+// A series of values over time, to be drawn as a single line.
+type chartSeries struct {
+	Name   string
+	Color  string
+	Dashed bool
+	Times  []time.Time
+	Values []float64
+}
+
+type ChartPoint struct {
+	X float64
+	Y float64
+}
+
+type ChartLine struct {
+	Name   string
+	Color  string
+	Dashed bool
+	// The line's points, formatted for the `points` attribute of an SVG polyline.
+	Points string
+	Dots   []ChartPoint
+}
+
+type ChartTick struct {
+	Position float64
+	Label    string
+}
+
+// A line chart, with every coordinate already computed, for rendering as SVG.
+type LineChart struct {
+	Width  int
+	Height int
+	// The edges of the plotted area.
+	Left   float64
+	Right  float64
+	Top    float64
+	Bottom float64
+	Lines  []ChartLine
+	XTicks []ChartTick
+	YTicks []ChartTick
+}
+
+// Lays out a chart of `series`, with values between `low` and `high`, spaced every `step` on the y axis.
+func buildLineChart(series []chartSeries, low float64, high float64, step float64) LineChart {
+	chart := LineChart{
+		Width:  chartWidth,
+		Height: chartHeight,
+		Left:   chartPadding,
+		Right:  chartWidth - chartPadding,
+		Top:    chartPadding / 2,
+		Bottom: chartHeight - chartPadding,
+	}
+
+	var first, last time.Time
+	for _, s := range series {
+		for _, t := range s.Times {
+			if first.IsZero() || t.Before(first) {
+				first = t
+			}
+			if last.IsZero() || t.After(last) {
+				last = t
+			}
+		}
+	}
+
+	x := func(t time.Time) float64 {
+		span := last.Sub(first)
+		if span <= 0 {
+			return (chart.Left + chart.Right) / 2
+		}
+		return chart.Left + (chart.Right-chart.Left)*float64(t.Sub(first))/float64(span)
+	}
+	y := func(value float64) float64 {
+		value = clamp(value, low, high)
+		return chart.Bottom - (chart.Bottom-chart.Top)*(value-low)/(high-low)
+	}
+
+	for value := low; value <= high; value += step {
+		chart.YTicks = append(chart.YTicks, ChartTick{Position: y(value), Label: strconv.Itoa(int(value))})
+	}
+
+	dates := map[string]bool{}
+	for _, s := range series {
+		line := ChartLine{Name: s.Name, Color: s.Color, Dashed: s.Dashed}
+		points := []string{}
+		for i, t := range s.Times {
+			point := ChartPoint{X: x(t), Y: y(s.Values[i])}
+			line.Dots = append(line.Dots, point)
+			points = append(points, fmt.Sprintf("%.1f,%.1f", point.X, point.Y))
+
+			label := t.Format("02/01")
+			if !dates[label] {
+				dates[label] = true
+				chart.XTicks = append(chart.XTicks, ChartTick{Position: point.X, Label: label})
+			}
+		}
+		line.Points = strings.Join(points, " ")
+		chart.Lines = append(chart.Lines, line)
+	}
+
+	return chart
+}
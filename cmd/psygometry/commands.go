@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Commands that can be run instead of the server, as `psygometry <command> [arguments...]`.
+var commands = map[string]func(args []string) error{
+	"export-pdf":  exportPDFCommand,
+	"import-exam": importExamCommand,
+	"item-stats":  itemStatsCommand,
+	"rescore":     rescoreCommand,
+}
+
+func runCommand(name string, args []string) error {
+	command, ok := commands[name]
+	if !ok {
+		names := []string{}
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return fmt.Errorf("unknown command %q (available commands: %s)", name, strings.Join(names, ", "))
+	}
+
+	return command(args)
+}
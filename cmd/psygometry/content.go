@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type BlockKind string
+
+const (
+	TextBlock  BlockKind = "text"
+	ImageBlock BlockKind = "image"
+	TableBlock BlockKind = "table"
+	MathBlock  BlockKind = "math"
+)
+
+// A piece of rich content in a question or an option, beyond its plain text.
+type Block struct {
+	Kind BlockKind
+	// The text of a text block, or the markup of a math block.
+	Text string
+	// Name of the asset shown by an image block, and the alternative text read in its place by screen readers.
+	Asset string
+	Alt   string
+	// Cells of a table block, where the first row is the header.
+	Rows [][]string
+}
+
+func (b Block) IsText() bool  { return b.Kind == TextBlock }
+func (b Block) IsImage() bool { return b.Kind == ImageBlock }
+func (b Block) IsTable() bool { return b.Kind == TableBlock }
+func (b Block) IsMath() bool  { return b.Kind == MathBlock }
+
+// The header of a table block, and the rows after it.
+func (b Block) Header() []string {
+	if len(b.Rows) == 0 {
+		return nil
+	}
+	return b.Rows[0]
+}
+
+func (b Block) Body() [][]string {
+	if len(b.Rows) == 0 {
+		return nil
+	}
+	return b.Rows[1:]
+}
+
+// An option as shown to the student: its text, followed by its rich content.
+type OptionContent struct {
+	Text   string
+	Blocks []Block
+}
+
+func (q Question) Option(index int) OptionContent {
+	content := OptionContent{Text: q.Options[index]}
+	if index < len(q.OptionBlocks) {
+		content.Blocks = q.OptionBlocks[index]
+	}
+	return content
+}
+
+func validateBlock(block Block, assets fs.FS) error {
+	switch block.Kind {
+	case TextBlock:
+		if block.Text == "" {
+			return errors.New("empty text block")
+		}
+		return validateText(block.Text)
+	case MathBlock:
+		if block.Text == "" {
+			return errors.New("empty math block")
+		}
+		_, err := renderMath(block.Text, true)
+		return err
+	case ImageBlock:
+		if block.Alt == "" {
+			return fmt.Errorf("image %q without alternative text", block.Asset)
+		}
+		if !fs.ValidPath(block.Asset) {
+			return fmt.Errorf("invalid asset name %q", block.Asset)
+		}
+		if _, err := fs.Stat(assets, block.Asset); err != nil {
+			return fmt.Errorf("missing asset %q", block.Asset)
+		}
+	case TableBlock:
+		if len(block.Rows) == 0 {
+			return errors.New("empty table block")
+		}
+		for _, row := range block.Rows {
+			if len(row) != len(block.Rows[0]) {
+				return errors.New("table rows of different lengths")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown block kind %q", block.Kind)
+	}
+
+	return nil
+}
+
+// Images and other files that questions refer to, by name. Resolved again in `main`, once the environment is loaded.
+var assets fs.FS = os.DirFS(assetsPath())
+
+// Path of the directory that assets are read from, taken from the `ASSETS_PATH` environment variable, or
+// `public/assets` when it is empty.
+func assetsPath() string {
+	if path := os.Getenv("ASSETS_PATH"); path != "" {
+		return path
+	}
+	return "public/assets"
+}
+
+// How long browsers may cache an asset without checking whether it changed.
+const assetMaxAge = 24 * time.Hour
+
+func registerAssets(e *echo.Echo) {
+	e.GET("/assets/*", func(c echo.Context) error {
+		name := c.Param("*")
+		if !fs.ValidPath(name) {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+
+		content, err := fs.ReadFile(assets, name)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+
+		// The ETag lets browsers revalidate an asset cheaply once it expires, even when the store has no modification times
+		hash := sha256.Sum256(content)
+		header := c.Response().Header()
+		header.Set("ETag", `"`+hex.EncodeToString(hash[:16])+`"`)
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(assetMaxAge.Seconds())))
+
+		http.ServeContent(c.Response(), c.Request(), name, time.Time{}, bytes.NewReader(content))
+		return nil
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Test: blocks are rejected when they are incomplete or refer to assets that do not exist
+func TestValidateBlock(t *testing.T) {
+	store := fstest.MapFS{"figure.svg": {Data: []byte("<svg/>")}}
+
+	cases := []struct {
+		block Block
+		valid bool
+	}{
+		{Block{Kind: TextBlock, Text: "text"}, true},
+		{Block{Kind: TextBlock}, false},
+		{Block{Kind: MathBlock, Text: "x^2"}, true},
+		{Block{Kind: ImageBlock, Asset: "figure.svg", Alt: "a figure"}, true},
+		{Block{Kind: ImageBlock, Asset: "figure.svg"}, false},
+		{Block{Kind: ImageBlock, Asset: "missing.svg", Alt: "a figure"}, false},
+		{Block{Kind: ImageBlock, Asset: "../figure.svg", Alt: "a figure"}, false},
+		{Block{Kind: TableBlock, Rows: [][]string{{"a", "b"}, {"1", "2"}}}, true},
+		{Block{Kind: TableBlock, Rows: [][]string{{"a", "b"}, {"1"}}}, false},
+		{Block{Kind: "video", Text: "text"}, false},
+	}
+
+	for _, c := range cases {
+		if err := validateBlock(c.block, store); (err == nil) != c.valid {
+			t.Errorf("%v: expected valid=%v, got %v", c.block, c.valid, err)
+		}
+	}
+}
+
+// Test: shuffled options keep their rich content
+func TestShuffleDisplay_optionBlocks(t *testing.T) {
+	question := Question{
+		Options:      []string{"a", "b", "c", "d"},
+		OptionBlocks: [][]Block{{{Kind: MathBlock, Text: "a"}}, nil, {{Kind: MathBlock, Text: "c"}}},
+	}
+	psychometry := Psychometry{Sections: []Section{{Questions: []Question{question}}}}
+	shuffle := Shuffle{Sections: []int{0}, Options: [][][]int{{{3, 2, 1, 0}}}}
+
+	displayed := shuffle.Display(psychometry).Sections[0].Questions[0]
+
+	for k, text := range displayed.Options {
+		blocks := displayed.Option(k).Blocks
+		if (text == "a" || text == "c") != (len(blocks) == 1 && blocks[0].Text == text) {
+			t.Errorf("option %q: unexpected content %v", text, blocks)
+		}
+	}
+}
+
+// Test: assets are served with cache headers, and revalidated by their ETag
+func TestRegisterAssets(t *testing.T) {
+	defer func(original fs.FS) { assets = original }(assets)
+	assets = fstest.MapFS{"figure.svg": {Data: []byte("<svg/>")}}
+
+	e := echo.New()
+	registerAssets(e)
+
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/assets/figure.svg", nil))
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Cache-Control") == "" || recorder.Body.String() != "<svg/>" {
+		t.Fatalf("expected the asset with cache headers, got %d %v", recorder.Code, recorder.Header())
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/assets/figure.svg", nil)
+	request.Header.Set("If-None-Match", recorder.Header().Get("ETag"))
+	recorder = httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotModified {
+		t.Errorf("expected a matching ETag to be revalidated, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/assets/missing.svg", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected a missing asset to be not found, got %d", recorder.Code)
+	}
+}
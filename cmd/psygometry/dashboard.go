@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// The most recent attempts shown in the topic heatmap.
+const heatmapAttempts = 10
+
+type HeatmapCell struct {
+	Answered int
+	Accuracy float64
+	// A background color from red (no correct answers) to green (only correct answers).
+	Color string
+}
+
+func (c HeatmapCell) Percent() float64 {
+	return c.Accuracy * 100
+}
+
+type HeatmapRow struct {
+	Topic Topic
+	Name  string
+	Cells []HeatmapCell
+}
+
+// Accuracy per topic (rows) in each of the student's recent attempts (columns).
+type TopicHeatmap struct {
+	Columns []string
+	Rows    []HeatmapRow
+}
+
+type AttemptRow struct {
+	Date     string
+	Duration string
+	Scores   Scores
+}
+
+type Dashboard struct {
+	Attempts      []AttemptRow
+	UniformChart  LineChart
+	GeneralChart  LineChart
+	Heatmap       TopicHeatmap
+	TotalTime     string
+	AverageTime   string
+	TestDate      string
+	HasProjection bool
+	Projection    int
+}
+
+var plannedTestDates = map[string]time.Time{}
+
+// The date a student plans to take the test on. Every change is persisted, and the latest one is kept.
+type PlannedTestDate struct {
+	Student string
+	Date    time.Time
+}
+
+// Path of a JSON lines file that planned test dates are persisted to, taken from the `TEST_DATES_PATH` environment
+// variable.
+//
+// When empty, planned test dates are only kept in memory.
+func testDatesPath() string {
+	return os.Getenv("TEST_DATES_PATH")
+}
+
+func recordTestDate(planned PlannedTestDate) error {
+	plannedTestDates[planned.Student] = planned.Date
+
+	if path := testDatesPath(); path != "" {
+		return appendJSONLines(path, []PlannedTestDate{planned})
+	}
+	return nil
+}
+
+// Keeps the latest date planned by every student.
+func rebuildTestDates(planned []PlannedTestDate) {
+	plannedTestDates = map[string]time.Time{}
+	for _, p := range planned {
+		plannedTestDates[p.Student] = p.Date
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// The midpoint of a general score range.
+func generalMidpoint(general [2]int) float64 {
+	return float64(general[0]+general[1]) / 2
+}
+
+// Projects the score at `target` by fitting a least-squares line to the scores so far.
+//
+// Returns false if there are not enough attempts (at least two, on different dates) to fit a line.
+func projectScore(times []time.Time, values []float64, target time.Time) (float64, bool) {
+	if len(times) < 2 {
+		return 0, false
+	}
+
+	days := func(t time.Time) float64 {
+		return t.Sub(times[0]).Hours() / 24
+	}
+
+	n := float64(len(times))
+	meanX, meanY := 0.0, 0.0
+	for i, t := range times {
+		meanX += days(t)
+		meanY += values[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	covariance, variance := 0.0, 0.0
+	for i, t := range times {
+		covariance += (days(t) - meanX) * (values[i] - meanY)
+		variance += (days(t) - meanX) * (days(t) - meanX)
+	}
+	if variance == 0 {
+		return 0, false
+	}
+
+	slope := covariance / variance
+	return clamp(meanY+slope*(days(target)-meanX), 200, 800), true
+}
+
+// Interpolates from red, through yellow, to green.
+func heatmapColor(accuracy float64) string {
+	red := math.Min(1, 2*(1-accuracy))
+	green := math.Min(1, 2*accuracy)
+	return fmt.Sprintf("#%02x%02x60", int(math.Round(80+175*red)), int(math.Round(80+175*green)))
+}
+
+func buildTopicHeatmap(studentAttempts []Attempt, history []Response) TopicHeatmap {
+	studentAttempts = studentAttempts[max(0, len(studentAttempts)-heatmapAttempts):]
+
+	columns := map[string]int{}
+	heatmap := TopicHeatmap{}
+	for i, attempt := range studentAttempts {
+		columns[attempt.Session] = i
+		heatmap.Columns = append(heatmap.Columns, attempt.Finished.Format("02/01"))
+	}
+
+	answered := map[Topic][]int{}
+	correct := map[Topic][]int{}
+	for _, response := range history {
+		column, ok := columns[response.Attempt]
+		if !ok {
+			continue
+		}
+		question, _, ok := findQuestion(response.QuestionID)
+		if !ok || question.Topic == "" {
+			continue
+		}
+
+		if answered[question.Topic] == nil {
+			answered[question.Topic] = make([]int, len(studentAttempts))
+			correct[question.Topic] = make([]int, len(studentAttempts))
+		}
+		answered[question.Topic][column] += 1
+		if response.Correct {
+			correct[question.Topic][column] += 1
+		}
+	}
+
+	for _, kind := range []SectionKind{V, Q, E} {
+		for _, topic := range sectionTopics[kind] {
+			if answered[topic] == nil {
+				continue
+			}
+
+			row := HeatmapRow{Topic: topic, Name: topicNames[topic]}
+			for column := range studentAttempts {
+				cell := HeatmapCell{Answered: answered[topic][column], Color: "#ddd"}
+				if cell.Answered > 0 {
+					cell.Accuracy = float64(correct[topic][column]) / float64(cell.Answered)
+					cell.Color = heatmapColor(cell.Accuracy)
+				}
+				row.Cells = append(row.Cells, cell)
+			}
+
+			// Topics belonging to more than one domain are only shown once
+			answered[topic] = nil
+			heatmap.Rows = append(heatmap.Rows, row)
+		}
+	}
+
+	return heatmap
+}
+
+func buildDashboard(student string, now time.Time) Dashboard {
+	history := studentAttempts(student)
+	dashboard := Dashboard{}
+
+	times := []time.Time{}
+	var v, q, e, multi, verbal, quantitative []float64
+	var total time.Duration
+	for _, attempt := range history {
+		scores := attempt.Summary.DynamicScores
+		duration := attempt.Finished.Sub(attempt.Started)
+		total += duration
+
+		times = append(times, attempt.Finished)
+		v = append(v, float64(scores.VUniform))
+		q = append(q, float64(scores.QUniform))
+		e = append(e, float64(scores.EUniform))
+		multi = append(multi, generalMidpoint(scores.MultiCategoryGeneral))
+		verbal = append(verbal, generalMidpoint(scores.VerbalFocusGeneral))
+		quantitative = append(quantitative, generalMidpoint(scores.QuantitativeFocusGeneral))
+
+		dashboard.Attempts = append(dashboard.Attempts, AttemptRow{
+			Date:     attempt.Finished.Format("02/01/2006"),
+			Duration: formatDuration(duration),
+			Scores:   scores,
+		})
+	}
+
+	dashboard.TotalTime = formatDuration(total)
+	if len(history) > 0 {
+		dashboard.AverageTime = formatDuration(total / time.Duration(len(history)))
+	}
+
+	dashboard.UniformChart = buildLineChart([]chartSeries{
+		{Name: "מילולי", Color: "#1f77b4", Times: times, Values: v},
+		{Name: "כמותי", Color: "#d62728", Times: times, Values: q},
+		{Name: "אנגלית", Color: "#2ca02c", Times: times, Values: e},
+	}, 50, 150, 25)
+
+	general := []chartSeries{
+		{Name: "רב-תחומי", Color: "#9467bd", Times: times, Values: multi},
+		{Name: "דגש מילולי", Color: "#1f77b4", Times: times, Values: verbal},
+		{Name: "דגש כמותי", Color: "#d62728", Times: times, Values: quantitative},
+	}
+
+	if testDate, ok := plannedTestDates[student]; ok {
+		dashboard.TestDate = testDate.Format(time.DateOnly)
+
+		projection, ok := projectScore(times, multi, testDate)
+		if ok && testDate.After(now) {
+			dashboard.HasProjection = true
+			dashboard.Projection = int(math.Round(projection))
+			general = append(general, chartSeries{
+				Name:   "תחזית רב-תחומי",
+				Color:  "#9467bd",
+				Dashed: true,
+				Times:  []time.Time{times[len(times)-1], testDate},
+				Values: []float64{multi[len(multi)-1], projection},
+			})
+		}
+	}
+
+	dashboard.GeneralChart = buildLineChart(general, 200, 800, 100)
+	dashboard.Heatmap = buildTopicHeatmap(history, studentResponses(student, ""))
+
+	return dashboard
+}
+
+func registerDashboard(e *echo.Echo) {
+	e.GET("/dashboard", func(c echo.Context) error {
+		return c.Render(http.StatusOK, "dashboard-page", buildDashboard(studentID(c), time.Now()))
+	})
+
+	e.POST("/dashboard/test-date", func(c echo.Context) error {
+		testDate, err := time.Parse(time.DateOnly, c.FormValue("date"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid date")
+		}
+
+		if err := recordTestDate(PlannedTestDate{Student: studentID(c), Date: testDate}); err != nil {
+			return err
+		}
+		return c.Redirect(http.StatusSeeOther, "/dashboard")
+	})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// Test: projecting scores that improve steadily continues the same trend
+func TestProjectScore_linear(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	times := []time.Time{start, start.Add(10 * day), start.Add(20 * day)}
+	values := []float64{400, 450, 500}
+
+	projection, ok := projectScore(times, values, start.Add(40*day))
+	if !ok || projection != 600 {
+		t.Errorf("expected a projection of 600, got %f (%v)", projection, ok)
+	}
+
+	projection, ok = projectScore(times, values, start.Add(1000*day))
+	if !ok || projection != 800 {
+		t.Errorf("expected the projection to be clamped to 800, got %f (%v)", projection, ok)
+	}
+
+	if _, ok := projectScore(times[:1], values[:1], start.Add(40*day)); ok {
+		t.Error("expected no projection from a single attempt")
+	}
+	if _, ok := projectScore([]time.Time{start, start}, values[:2], start.Add(40*day)); ok {
+		t.Error("expected no projection from attempts on the same date")
+	}
+}
+
+// Test: every point of a chart is within its plotted area
+func TestBuildLineChart_bounds(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	bounds := func(offsets []uint16, values []int16) bool {
+		series := chartSeries{}
+		for i, offset := range offsets {
+			if i >= len(values) {
+				break
+			}
+			series.Times = append(series.Times, start.Add(time.Duration(offset)*time.Hour))
+			series.Values = append(series.Values, float64(values[i]))
+		}
+
+		chart := buildLineChart([]chartSeries{series}, 50, 150, 25)
+		for _, point := range chart.Lines[0].Dots {
+			if point.X < chart.Left || point.X > chart.Right || point.Y < chart.Top || point.Y > chart.Bottom {
+				return false
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(bounds, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: the heatmap shows the accuracy of every topic in every attempt
+func TestBuildTopicHeatmap(t *testing.T) {
+	question, _, _ := findQuestion("fake-0-0")
+	history := []Attempt{{Session: "first"}, {Session: "second"}}
+	recorded := []Response{
+		{Attempt: "first", QuestionID: question.ID, Correct: true},
+		{Attempt: "second", QuestionID: question.ID, Correct: false},
+		{Attempt: "other", QuestionID: question.ID, Correct: false},
+	}
+
+	heatmap := buildTopicHeatmap(history, recorded)
+
+	if len(heatmap.Rows) != 1 || heatmap.Rows[0].Topic != question.Topic {
+		t.Fatalf("expected a single row for %q, got %v", question.Topic, heatmap.Rows)
+	}
+	cells := heatmap.Rows[0].Cells
+	if cells[0].Accuracy != 1 || cells[1].Accuracy != 0 || cells[1].Answered != 1 {
+		t.Errorf("unexpected cells %v", cells)
+	}
+}
+
+// Test: the latest date planned by every student is loaded back from the persisted dates
+func TestRebuildTestDates(t *testing.T) {
+	defer func(original map[string]time.Time) {
+		plannedTestDates = original
+	}(plannedTestDates)
+	plannedTestDates = map[string]time.Time{}
+
+	path := filepath.Join(t.TempDir(), "test-dates.jsonl")
+	t.Setenv("TEST_DATES_PATH", path)
+
+	first := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	for _, planned := range []PlannedTestDate{{"a", first}, {"b", first}, {"a", first.Add(30 * day)}} {
+		if err := recordTestDate(planned); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recorded := plannedTestDates
+
+	loaded, err := loadJSONLines[PlannedTestDate](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rebuildTestDates(loaded)
+
+	if !reflect.DeepEqual(plannedTestDates, recorded) || !plannedTestDates["a"].Equal(first.Add(30*day)) {
+		t.Errorf("expected the latest dates %v, got %v", recorded, plannedTestDates)
+	}
+}
@@ -2,15 +2,62 @@ package main
 
 import (
 	"errors"
+	"math/rand"
 	"net/url"
 	"regexp"
 	"strconv"
+	"time"
 )
 
+type Topic string
+
+const (
+	Analogies            Topic = "analogies"
+	SentenceCompletion   Topic = "sentence-completion"
+	Logic                Topic = "logic"
+	ReadingComprehension Topic = "reading-comprehension"
+	Algebra              Topic = "algebra"
+	Geometry             Topic = "geometry"
+	GraphsAndTables      Topic = "graphs-and-tables"
+	Restatements         Topic = "restatements"
+)
+
+var topicNames = map[Topic]string{
+	Analogies:            "אנלוגיות",
+	SentenceCompletion:   "השלמת משפטים",
+	Logic:                "חשיבה לוגית",
+	ReadingComprehension: "הבנת הנקרא",
+	Algebra:              "אלגברה",
+	Geometry:             "גאומטריה",
+	GraphsAndTables:      "הסקה מתרשימים",
+	Restatements:         "ניסוח מחדש",
+}
+
+// The topics questions of each domain may be tagged with.
+var sectionTopics = map[SectionKind][]Topic{
+	V: {Analogies, SentenceCompletion, Logic, ReadingComprehension},
+	Q: {Algebra, Geometry, GraphsAndTables},
+	E: {SentenceCompletion, Restatements, ReadingComprehension},
+}
+
 type Question struct {
+	// Identifies the question across psychometries, for keeping track of the answers given to it.
+	ID            string
 	Content       string
-	Options       [4]string
+	Options       []string
 	CorrectOption int
+	Topic         Topic
+	// ID of the passage of the section that the question refers to, if any.
+	PassageID string
+	// Rich content shown after the text of the question, such as figures and tables.
+	Blocks []Block
+	// Rich content shown after the text of each option, by the option's index.
+	OptionBlocks [][]Block
+	// Multiple-choice when empty. The fields below are only used by other types of questions.
+	Type           ItemType
+	NumericAnswer  float64
+	Tolerance      float64
+	CorrectOptions []int
 }
 
 type SectionKind string
@@ -26,10 +73,11 @@ type Section struct {
 	Index     int
 	IsCounted bool
 	Questions []Question
+	Passages  []Passage
 }
 
 type Psychometry struct {
-	WritingSection string
+	WritingSection WritingPrompt
 	Sections       []Section
 }
 
@@ -48,6 +96,11 @@ func (p *Psychometry) GetSections(kind SectionKind) []Section {
 type PsychometryAnswers struct {
 	WritingSection string
 	Sections       [][]int
+	// Time spent on every question, as reported by the client. Zero when unknown.
+	Timings [][]time.Duration
+	// Answers to questions that are not multiple-choice, in a canonical form (a number, or comma-separated options).
+	// Empty when unanswered.
+	Entries [][]string
 }
 
 func (a *PsychometryAnswers) GetSections(psychometry Psychometry, kind SectionKind) [][]int {
@@ -62,18 +115,72 @@ func (a *PsychometryAnswers) GetSections(psychometry Psychometry, kind SectionKi
 	return answerSections
 }
 
+func (a *PsychometryAnswers) GetTimings(psychometry Psychometry, kind SectionKind) [][]time.Duration {
+	timingSections := [][]time.Duration{}
+
+	for i, section := range psychometry.Sections {
+		if section.Kind == kind && section.IsCounted {
+			timings := make([]time.Duration, len(section.Questions))
+			for j := range timings {
+				timings[j] = a.Timing(i, j)
+			}
+			timingSections = append(timingSections, timings)
+		}
+	}
+
+	return timingSections
+}
+
+func (a *PsychometryAnswers) GetEntries(psychometry Psychometry, kind SectionKind) [][]string {
+	entrySections := [][]string{}
+
+	for i, section := range psychometry.Sections {
+		if section.Kind == kind && section.IsCounted {
+			entries := make([]string, len(section.Questions))
+			for j := range entries {
+				entries[j] = a.Entry(i, j)
+			}
+			entrySections = append(entrySections, entries)
+		}
+	}
+
+	return entrySections
+}
+
+// The answer to a question that is not multiple-choice, or an empty string if it was not answered.
+func (a *PsychometryAnswers) Entry(sIndex int, qIndex int) string {
+	if sIndex >= len(a.Entries) || qIndex >= len(a.Entries[sIndex]) {
+		return ""
+	}
+	return a.Entries[sIndex][qIndex]
+}
+
+// The time spent on a question, or zero if it is unknown.
+func (a *PsychometryAnswers) Timing(sIndex int, qIndex int) time.Duration {
+	if sIndex >= len(a.Timings) || qIndex >= len(a.Timings[sIndex]) {
+		return 0
+	}
+	return a.Timings[sIndex][qIndex]
+}
+
 func newPsychometryAnswers(psychometry Psychometry) PsychometryAnswers {
 	answerSections := make([][]int, len(psychometry.Sections))
+	timings := make([][]time.Duration, len(psychometry.Sections))
+	entries := make([][]string, len(psychometry.Sections))
 	for i, section := range psychometry.Sections {
 		answerSections[i] = make([]int, len(section.Questions))
 		for j := range answerSections[i] {
 			answerSections[i][j] = -1
 		}
+		timings[i] = make([]time.Duration, len(section.Questions))
+		entries[i] = make([]string, len(section.Questions))
 	}
 
 	answers := PsychometryAnswers{
 		WritingSection: "",
 		Sections:       answerSections,
+		Timings:        timings,
+		Entries:        entries,
 	}
 	return answers
 }
@@ -84,40 +191,100 @@ var (
 	InvalidIndex  = errors.New("invalid index")
 )
 
-func ParsePsychometryAnswers(form url.Values, psychometry Psychometry) (*PsychometryAnswers, error) {
-	answers := newPsychometryAnswers(psychometry)
+var formKeyRegexp = regexp.MustCompile("[\\][.]+")
+
+// Splits a form key into its path (e.g. `Sections[0][1]` into `Sections`, `0`, and `1`).
+func splitFormKey(key string) []string {
+	return formKeyRegexp.Split(key, -1)
+}
 
-	r := regexp.MustCompile("[\\][.]+")
+// Parses the displayed section and question indexes of a form key (e.g. `Sections[0][1]`), which has already been
+// split.
+func parseQuestionIndexes(path []string, psychometry Psychometry, shuffle Shuffle) (int, int, error) {
+	if len(path) < 2 {
+		return 0, 0, MissingIndex
+	}
+	sIndex, err := strconv.Atoi(path[1])
+	if err != nil {
+		return 0, 0, DeformedIndex
+	}
+	if sIndex < 0 || sIndex >= len(psychometry.Sections) {
+		return 0, 0, InvalidIndex
+	}
+
+	if len(path) < 3 {
+		return 0, 0, MissingIndex
+	}
+	qIndex, err := strconv.Atoi(path[2])
+	if err != nil {
+		return 0, 0, DeformedIndex
+	}
+	if qIndex < 0 || qIndex >= len(psychometry.Sections[shuffle.section(sIndex)].Questions) {
+		return 0, 0, InvalidIndex
+	}
+
+	return sIndex, qIndex, nil
+}
+
+func ParsePsychometryAnswers(form url.Values, psychometry Psychometry, shuffle Shuffle) (*PsychometryAnswers, error) {
+	answers := newPsychometryAnswers(psychometry)
 
 	for key := range form {
-		path := r.Split(key, -1)
+		path := splitFormKey(key)
 
 		if path[0] == "WritingSection" {
 			answers.WritingSection = form.Get(key)
 			continue
 		}
 
-		if path[0] != "Sections" {
+		if path[0] == "Timings" {
+			sIndex, qIndex, err := parseQuestionIndexes(path, psychometry, shuffle)
+			if err != nil {
+				return nil, err
+			}
+			sIndex = shuffle.section(sIndex)
+
+			milliseconds, err := strconv.Atoi(form.Get(key))
+			if err != nil {
+				return nil, DeformedIndex
+			}
+			if milliseconds < 0 {
+				return nil, InvalidIndex
+			}
+
+			answers.Timings[sIndex][qIndex] = time.Duration(milliseconds) * time.Millisecond
 			continue
 		}
 
-		if len(path) < 2 {
-			return nil, MissingIndex
+		if path[0] != "Sections" {
+			continue
 		}
-		sIndex, err := strconv.Atoi(path[1])
+
+		sIndex, qIndex, err := parseQuestionIndexes(path, psychometry, shuffle)
 		if err != nil {
-			return nil, DeformedIndex
-		}
-		if sIndex < 0 || sIndex >= len(psychometry.Sections) {
-			return nil, InvalidIndex
+			return nil, err
 		}
+		question := shuffle.question(psychometry, sIndex, qIndex)
+		sIndex = shuffle.section(sIndex)
 
-		if len(path) < 3 {
-			return nil, MissingIndex
-		}
-		qIndex, err := strconv.Atoi(path[2])
-		if err != nil {
-			return nil, DeformedIndex
+		switch question.Type {
+		case NumericEntry:
+			entry, err := parseNumericEntry(form.Get(key))
+			if err != nil {
+				return nil, err
+			}
+			answers.Entries[sIndex][qIndex] = entry
+			continue
+		case MultipleSelect:
+			options, err := parseMultipleSelect(form[key], question)
+			if err != nil {
+				return nil, err
+			}
+			for i, option := range options {
+				options[i] = shuffle.option(sIndex, qIndex, option)
+			}
+			answers.Entries[sIndex][qIndex] = formatOptions(options)
+			continue
 		}
 
 		rawValue := form.Get(key)
@@ -130,23 +297,71 @@ func ParsePsychometryAnswers(form url.Values, psychometry Psychometry) (*Psychom
 				return nil, DeformedIndex
 			}
 		}
-		if value < 0 || value > 4 {
-			return nil, InvalidIndex
-		}
-
-		if qIndex < 0 || qIndex >= len(answers.Sections[sIndex]) {
+		if value < 0 || value >= len(question.Options) {
 			return nil, InvalidIndex
 		}
 
-		answers.Sections[sIndex][qIndex] = value
+		answers.Sections[sIndex][qIndex] = shuffle.option(sIndex, qIndex, value)
 	}
 
 	return &answers, nil
 }
 
+// A question in the bank, along with the kind of section it belongs to.
+type bankQuestion struct {
+	Question Question
+	Kind     SectionKind
+}
+
+// The sections that questions for practice are drawn from, with an index of their questions by ID.
+type questionBank struct {
+	sections  []Section
+	questions map[string]bankQuestion
+}
+
+// Builds the bank from the fake data and the latest version of every published exam.
+func newQuestionBank(published []Exam) questionBank {
+	sections := generateFakeData().Sections
+	for _, exam := range published {
+		sections = append(sections, exam.Psychometry.Sections...)
+	}
+
+	questions := map[string]bankQuestion{}
+	for _, section := range sections {
+		for _, question := range section.Questions {
+			if _, ok := questions[question.ID]; !ok {
+				questions[question.ID] = bankQuestion{Question: question, Kind: section.Kind}
+			}
+		}
+	}
+
+	return questionBank{sections: sections, questions: questions}
+}
+
+// Must be rebuilt with `rebuildBank` whenever `exams` changes.
+var practiceBank = newQuestionBank(latestExams())
+
+func rebuildBank() {
+	practiceBank = newQuestionBank(latestExams())
+}
+
+// The sections that questions for practice are drawn from.
+func bankSections() []Section {
+	return practiceBank.sections
+}
+
+// Finds a question in the bank by its ID, along with the kind of section it belongs to.
+func findQuestion(id string) (Question, SectionKind, bool) {
+	found, ok := practiceBank.questions[id]
+	return found.Question, found.Kind, ok
+}
+
 func generateFakeData() Psychometry {
+	// Any prompt matches when neither the genre nor the difficulty are restricted
+	writingPrompt, _ := RandomWritingPrompt(rand.New(rand.NewSource(time.Now().UnixNano())), "", 0)
+
 	psychometry := Psychometry{
-		WritingSection: "נא לכתוב חיבור על החשיבות של סיפור סיפורים בקולנוע המודרני.",
+		WritingSection: writingPrompt,
 		Sections: []Section{
 			{
 				Kind:      V,
@@ -154,14 +369,18 @@ func generateFakeData() Psychometry {
 				IsCounted: true,
 				Questions: []Question{
 					{
+						ID:            "fake-0-0",
 						Content:       "מי משחק את הדמות הראשית בסרט 'ההסתערות'?",
-						Options:       [4]string{"ליאונרדו דיקפריו", "בראד פיט", "טום הנקס", "ג'וני דפ"},
+						Options:       []string{"ליאונרדו דיקפריו", "בראד פיט", "טום הנקס", "ג'וני דפ"},
 						CorrectOption: 0,
+						Topic:         Analogies,
 					},
 					{
+						ID:            "fake-0-1",
 						Content:       "איזה סרט לא נבחר על ידי כריסטופר נולן?",
-						Options:       [4]string{"ההסתערות", "בלונדינית משפטית", "בין הכוכבים", "אי הצנום"},
+						Options:       []string{"ההסתערות", "בלונדינית משפטית", "בין הכוכבים", "אי הצנום"},
 						CorrectOption: 1,
+						Topic:         SentenceCompletion,
 					},
 				},
 			},
@@ -171,14 +390,32 @@ func generateFakeData() Psychometry {
 				IsCounted: true,
 				Questions: []Question{
 					{
+						ID:            "fake-1-0",
 						Content:       "מי הוא המחבר של סדרת הספרים 'משחקי הכס'?",
-						Options:       [4]string{"ג'יי. קי. רואלינג", "סטיבן קינג", "ג'ורג' אר.אר. מרטין", "ג'יי.אר.אר. טולקין"},
+						Options:       []string{"ג'יי. קי. רואלינג", "סטיבן קינג", "ג'ורג' אר.אר. מרטין", "ג'יי.אר.אר. טולקין"},
 						CorrectOption: 2,
+						Topic:         Logic,
 					},
 					{
-						Content:       "איזו סדרת ספרים כוללת דמות בשם 'הארי פוטר'?",
-						Options:       [4]string{"הארי פוטר", "אדון הטבעות", "משחקי הכס", "המשחקים של הרעב"},
+						ID:            "fake-1-1",
+						Content:       "לפי שורות 3-4, לאיזו סדרת ספרים הפך הספר הראשון?",
+						Options:       []string{"הארי פוטר", "אדון הטבעות", "משחקי הכס", "המשחקים של הרעב"},
 						CorrectOption: 0,
+						Topic:         ReadingComprehension,
+						PassageID:     "fake-passage-1",
+					},
+				},
+				Passages: []Passage{
+					{
+						ID:    "fake-passage-1",
+						Title: "הילד שנשאר בחיים",
+						Lines: []string{
+							"ג'יי. קי. רואלינג כתבה את הספר הראשון בסדרה",
+							"בבתי קפה באדינבורו, כשהיא אם חד-הורית.",
+							"שנים-עשר מו״לים דחו את כתב היד, עד שהספר",
+							"ראה אור ב-1997, והפך לסדרת שבעת ספרי הארי פוטר,",
+							"שנמכרו ביותר מחצי מיליארד עותקים ברחבי העולם.",
+						},
 					},
 				},
 			},
@@ -188,14 +425,18 @@ func generateFakeData() Psychometry {
 				IsCounted: true,
 				Questions: []Question{
 					{
+						ID:            "fake-2-0",
 						Content:       "איזה אבנג'ר מכונה בגלל המראה הירוק שלו והכוח המדהים שלו?",
-						Options:       [4]string{"איירון מן", "קפטן אמריקה", "תור", "האלק"},
+						Options:       []string{"איירון מן", "קפטן אמריקה", "תור", "האלק"},
 						CorrectOption: 3,
+						Topic:         Algebra,
 					},
 					{
+						ID:            "fake-2-1",
 						Content:       "מי מגלם את הדמות של נרייט שחורה ביקום הסרטים המרובע של מארו?",
-						Options:       [4]string{"סקרלט יוהנסון", "גל גדות", "אנג'לינה ג'ולי", "ג'ניפר לורנס"},
+						Options:       []string{"סקרלט יוהנסון", "גל גדות", "אנג'לינה ג'ולי", "ג'ניפר לורנס"},
 						CorrectOption: 0,
+						Topic:         Geometry,
 					},
 				},
 			},
@@ -205,14 +446,30 @@ func generateFakeData() Psychometry {
 				IsCounted: true,
 				Questions: []Question{
 					{
-						Content:       "איזה להקה מפורסמת בשיר 'בוהמיאן ראפסודיה'?",
-						Options:       [4]string{"הביטלס", "לד זפלין", "קווין", "פלוויד הוויד"},
+						ID:            "fake-3-0",
+						Content:       "לפי הטבלה, איזו להקה מכרה את מספר האלבומים הגדול ביותר?",
+						Options:       []string{"הביטלס", "לד זפלין", "קווין", "פלוויד הוויד"},
 						CorrectOption: 2,
+						Topic:         GraphsAndTables,
+						Blocks: []Block{
+							{
+								Kind: TableBlock,
+								Rows: [][]string{
+									{"להקה", "אלבומים שנמכרו (במיליונים)"},
+									{"הביטלס", "183"},
+									{"לד זפלין", "112"},
+									{"קווין", "250"},
+									{"פלוויד הוויד", "75"},
+								},
+							},
+						},
 					},
 					{
+						ID:            "fake-3-1",
 						Content:       "איזה סרט לעיתים קרוא 'הסרט הגדול ביותר שנעשה אי פעם'?",
-						Options:       [4]string{"הקרוטונאי", "פיקדון דמים", "בראש ובראש", "פנים שטוחות"},
+						Options:       []string{"הקרוטונאי", "פיקדון דמים", "בראש ובראש", "פנים שטוחות"},
 						CorrectOption: 0,
+						Topic:         Algebra,
 					},
 				},
 			},
@@ -222,14 +479,18 @@ func generateFakeData() Psychometry {
 				IsCounted: true,
 				Questions: []Question{
 					{
+						ID:            "fake-4-0",
 						Content:       "מי צייר את היצירה המפורסמת 'לילה כוכבי'?",
-						Options:       [4]string{"מונה", "ואן גוך", "פיקאסו", "דה וינצ'י"},
+						Options:       []string{"מונה", "ואן גוך", "פיקאסו", "דה וינצ'י"},
 						CorrectOption: 1,
+						Topic:         SentenceCompletion,
 					},
 					{
+						ID:            "fake-4-1",
 						Content:       "איזה מלחין מוכר כ 'הגאון'?",
-						Options:       [4]string{"מוצארט", "בטהובן", "באך", "שופין"},
+						Options:       []string{"מוצארט", "בטהובן", "באך", "שופין"},
 						CorrectOption: 0,
+						Topic:         Restatements,
 					},
 				},
 			},
@@ -239,14 +500,18 @@ func generateFakeData() Psychometry {
 				IsCounted: true,
 				Questions: []Question{
 					{
+						ID:            "fake-5-0",
 						Content:       "מי זכתה בפרס אוסקר לשחקנית הטובה ביותר על תפקידה ב'ברבור שחור'?",
-						Options:       [4]string{"מריל סטריפ", "קייט בלנשט", "ג'וליאן מור", "נטלי פורטמן"},
+						Options:       []string{"מריל סטריפ", "קייט בלנשט", "ג'וליאן מור", "נטלי פורטמן"},
 						CorrectOption: 3,
+						Topic:         ReadingComprehension,
 					},
 					{
+						ID:            "fake-5-1",
 						Content:       "איזה במאי ידוע בסרטיו האפיים כמו 'רשימת שינדלר' ו'שמור פרטי'?",
-						Options:       [4]string{"סטיבן שפילברג", "מרטין סקורסזה", "קוונטין טרנטינו", "כריסטופר נולן"},
+						Options:       []string{"סטיבן שפילברג", "מרטין סקורסזה", "קוונטין טרנטינו", "כריסטופר נולן"},
 						CorrectOption: 0,
+						Topic:         Restatements,
 					},
 				},
 			},
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const defaultDrillLength = 10
+
+// A practice session of a single section, outside of a full psychometry.
+type Drill struct {
+	Session string
+	// Holds the drilled section as its only section, so that answers can be parsed as they are for a full
+	// psychometry.
+	Psychometry Psychometry
+}
+
+type QuestionFeedback struct {
+	Question Question
+	// The option chosen by the student, or -1 if the question was not answered or is not multiple-choice.
+	Chosen int
+	// The answer given to a question that is not multiple-choice.
+	Entry   string
+	Correct bool
+}
+
+func (f QuestionFeedback) Answer() template.HTML {
+	return f.Question.DescribeAnswer(f.Chosen, f.Entry)
+}
+
+type DrillResult struct {
+	Section  Section
+	Raw      int
+	Uniform  int
+	Feedback []QuestionFeedback
+}
+
+type DrillOptions struct {
+	Kinds  []SectionKind
+	Topics map[SectionKind][]Topic
+	Names  map[Topic]string
+	Length int
+}
+
+var drills = map[string]*Drill{}
+
+// Builds a section of up to `length` questions of a certain kind, drawn at random from `sections`.
+//
+// When `topic` is not empty, only questions tagged with it are drawn.
+func buildDrillSection(rand *rand.Rand, sections []Section, kind SectionKind, topic Topic, length int) Section {
+	candidates := []Question{}
+	for _, section := range sections {
+		if section.Kind != kind {
+			continue
+		}
+
+		for _, question := range section.Questions {
+			if topic == "" || question.Topic == topic {
+				candidates = append(candidates, question)
+			}
+		}
+	}
+
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	section := Section{
+		Kind:      kind,
+		Index:     0,
+		IsCounted: true,
+		Questions: candidates[:min(length, len(candidates))],
+	}
+	return withPassages(section, sections)
+}
+
+func calculateDrillResult(section Section, answers []int, entries []string) DrillResult {
+	result := DrillResult{Section: section}
+
+	result.Raw = rawCategoryScore([]Section{section}, [][]int{answers}, [][]string{entries})
+	result.Uniform = uniformCategoryScore([]Section{section}, result.Raw)
+
+	result.Feedback = questionFeedback(section, answers, entries)
+
+	return result
+}
+
+func questionFeedback(section Section, answers []int, entries []string) []QuestionFeedback {
+	feedback := []QuestionFeedback{}
+	for i, question := range section.Questions {
+		feedback = append(feedback, QuestionFeedback{
+			Question: question,
+			Chosen:   answers[i],
+			Entry:    entries[i],
+			Correct:  question.IsCorrect(answers[i], entries[i]),
+		})
+	}
+	return feedback
+}
+
+func registerDrills(e *echo.Echo) {
+	e.GET("/drill", func(c echo.Context) error {
+		options := DrillOptions{
+			Kinds:  []SectionKind{V, Q, E},
+			Topics: sectionTopics,
+			Names:  topicNames,
+			Length: defaultDrillLength,
+		}
+		return c.Render(http.StatusOK, "drill-options-page", options)
+	})
+
+	e.GET("/drill/start", func(c echo.Context) error {
+		req := c.Request()
+		if err := req.ParseForm(); err != nil {
+			return err
+		}
+
+		kind := SectionKind(req.Form.Get("kind"))
+		if _, ok := sectionTopics[kind]; !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, "unknown section kind")
+		}
+
+		length := defaultDrillLength
+		if rawLength := req.Form.Get("length"); rawLength != "" {
+			var err error
+			length, err = strconv.Atoi(rawLength)
+			if err != nil || length <= 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid length")
+			}
+		}
+
+		random := rand.New(rand.NewSource(time.Now().UnixNano()))
+		section := buildDrillSection(random, bankSections(), kind, Topic(req.Form.Get("topic")), length)
+		if len(section.Questions) == 0 {
+			return echo.NewHTTPError(http.StatusNotFound, "no questions match the drill")
+		}
+
+		drill := &Drill{
+			Session:     uuid.New().String(),
+			Psychometry: Psychometry{Sections: []Section{section}},
+		}
+		drills[drill.Session] = drill
+
+		return c.Render(http.StatusOK, "drill-page", drill)
+	})
+
+	e.POST("/drill/answers", func(c echo.Context) error {
+		req := c.Request()
+		if err := req.ParseForm(); err != nil {
+			return err
+		}
+
+		drill, ok := drills[req.Form.Get("session")]
+		if !ok {
+			// TODO: handle this
+			return errors.New("invalid session")
+		}
+
+		answers, err := ParsePsychometryAnswers(req.Form, drill.Psychometry, Shuffle{})
+		if err != nil {
+			return err
+		}
+
+		delete(drills, drill.Session)
+		if err := recordResponses(studentID(c), drill.Session, drill.Psychometry, *answers); err != nil {
+			return err
+		}
+
+		result := calculateDrillResult(drill.Psychometry.Sections[0], answers.Sections[0], answers.Entries[0])
+		return c.Render(http.StatusCreated, "drill-results", result)
+	})
+}
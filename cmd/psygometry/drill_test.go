@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"testing/quick"
+)
+
+// Test: a drilled section only ever contains questions of the requested kind and topic, up to the requested length
+func TestBuildDrillSection_matches(t *testing.T) {
+	kinds := []SectionKind{V, Q, E}
+
+	matches := func(psychometry Psychometry, seed int64, kindIndex uint, topicIndex uint, length uint8) bool {
+		kind := kinds[kindIndex%uint(len(kinds))]
+		topics := append([]Topic{""}, sectionTopics[kind]...)
+		topic := topics[topicIndex%uint(len(topics))]
+
+		for i := range psychometry.Sections {
+			for j := range psychometry.Sections[i].Questions {
+				psychometry.Sections[i].Questions[j].Topic = topics[(i+j)%len(topics)]
+			}
+		}
+
+		section := buildDrillSection(rand.New(rand.NewSource(seed)), psychometry.Sections, kind, topic, int(length))
+		if section.Kind != kind || len(section.Questions) > int(length) {
+			return false
+		}
+
+		for _, question := range section.Questions {
+			if topic != "" && question.Topic != topic {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if err := quick.Check(matches, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: drill results mark exactly the questions answered with their correct option
+func TestCalculateDrillResult_feedback(t *testing.T) {
+	section := generateFakeData().Sections[0]
+	answers := []int{section.Questions[0].CorrectOption, -1}
+
+	result := calculateDrillResult(section, answers, []string{"", ""})
+
+	if result.Raw != 1 {
+		t.Errorf("expected a raw score of 1, got %d", result.Raw)
+	}
+	if !result.Feedback[0].Correct || result.Feedback[1].Correct {
+		t.Errorf("unexpected feedback %v", result.Feedback)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// The time given for the writing task in the real PET.
+const essayMinutes = 30
+
+type EssayDraft struct {
+	Writing string
+	Score   WritingScore
+}
+
+// A single prompt being practiced, across all of the drafts written for it.
+type EssayPractice struct {
+	Session string
+	Prompt  WritingPrompt
+	Drafts  []EssayDraft
+}
+
+type DiffKind int
+
+const (
+	DiffSame DiffKind = iota
+	DiffAdded
+	DiffRemoved
+)
+
+func (k DiffKind) IsAdded() bool   { return k == DiffAdded }
+func (k DiffKind) IsRemoved() bool { return k == DiffRemoved }
+
+type DiffChunk struct {
+	Kind DiffKind
+	Text string
+}
+
+// The result of grading a draft, compared to the draft before it.
+type EssayDraftView struct {
+	Session         string
+	Draft           EssayDraft
+	Previous        *EssayDraft
+	Diff            []DiffChunk
+	LinguisticDelta int
+	ContentDelta    int
+}
+
+type EssayPracticePage struct {
+	Practice          *EssayPractice
+	Minutes           int
+	MinimumLines      int
+	MaximumLines      int
+	CharactersPerLine int
+	LatestWriting     string
+}
+
+var essayPractices = map[string]*EssayPractice{}
+
+// Computes a word-by-word diff between two drafts, based on their longest common subsequence of words.
+func diffWords(previous string, current string) []DiffChunk {
+	a := strings.Fields(previous)
+	b := strings.Fields(current)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	chunks := []DiffChunk{}
+	push := func(kind DiffKind, word string) {
+		if len(chunks) > 0 && chunks[len(chunks)-1].Kind == kind {
+			chunks[len(chunks)-1].Text += " " + word
+			return
+		}
+		chunks = append(chunks, DiffChunk{Kind: kind, Text: word})
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			push(DiffSame, a[i])
+			i += 1
+			j += 1
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			push(DiffRemoved, a[i])
+			i += 1
+		} else {
+			push(DiffAdded, b[j])
+			j += 1
+		}
+	}
+	for ; i < len(a); i++ {
+		push(DiffRemoved, a[i])
+	}
+	for ; j < len(b); j++ {
+		push(DiffAdded, b[j])
+	}
+
+	return chunks
+}
+
+func newEssayDraftView(practice *EssayPractice) EssayDraftView {
+	draft := practice.Drafts[len(practice.Drafts)-1]
+	view := EssayDraftView{Session: practice.Session, Draft: draft}
+
+	if len(practice.Drafts) > 1 {
+		previous := practice.Drafts[len(practice.Drafts)-2]
+		view.Previous = &previous
+		view.Diff = diffWords(previous.Writing, draft.Writing)
+		view.LinguisticDelta = draft.Score.Linguistic - previous.Score.Linguistic
+		view.ContentDelta = draft.Score.Content - previous.Score.Content
+	}
+
+	return view
+}
+
+func newEssayPracticePage(practice *EssayPractice) EssayPracticePage {
+	page := EssayPracticePage{
+		Practice:          practice,
+		Minutes:           essayMinutes,
+		MinimumLines:      minimumLines,
+		MaximumLines:      maximumLines,
+		CharactersPerLine: charactersPerLine,
+	}
+	if len(practice.Drafts) > 0 {
+		page.LatestWriting = practice.Drafts[len(practice.Drafts)-1].Writing
+	}
+	return page
+}
+
+func registerEssayPractice(e *echo.Echo) {
+	e.GET("/essay", func(c echo.Context) error {
+		return c.Render(http.StatusOK, "essay-prompts-page", writingPrompts)
+	})
+
+	e.GET("/essay/write", func(c echo.Context) error {
+		req := c.Request()
+		if err := req.ParseForm(); err != nil {
+			return err
+		}
+
+		session := req.Form.Get("session")
+		practice, ok := essayPractices[session]
+		if !ok {
+			var prompt WritingPrompt
+			if id := req.Form.Get("prompt"); id != "" {
+				prompt, ok = GetWritingPrompt(id)
+				if !ok {
+					return echo.NewHTTPError(http.StatusNotFound, "unknown prompt")
+				}
+			} else {
+				random := rand.New(rand.NewSource(time.Now().UnixNano()))
+				prompt, ok = RandomWritingPrompt(random, WritingGenre(req.Form.Get("genre")), 0)
+				if !ok {
+					return echo.NewHTTPError(http.StatusNotFound, "no prompt matches")
+				}
+			}
+
+			practice = &EssayPractice{
+				Session: uuid.New().String(),
+				Prompt:  prompt,
+			}
+			essayPractices[practice.Session] = practice
+		}
+
+		return c.Render(http.StatusOK, "essay-page", newEssayPracticePage(practice))
+	})
+
+	e.POST("/essay/drafts", func(c echo.Context) error {
+		req := c.Request()
+		if err := req.ParseForm(); err != nil {
+			return err
+		}
+
+		practice, ok := essayPractices[req.Form.Get("session")]
+		if !ok {
+			// TODO: handle this
+			return errors.New("invalid session")
+		}
+
+		writing := req.Form.Get("WritingSection")
+		score, err := calculateWritingScore(practice.Prompt, writing)
+		if err != nil {
+			return err
+		}
+
+		practice.Drafts = append(practice.Drafts, EssayDraft{Writing: writing, Score: *score})
+
+		return c.Render(http.StatusCreated, "essay-draft", newEssayDraftView(practice))
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func joinDiff(chunks []DiffChunk, skip DiffKind) string {
+	words := []string{}
+	for _, chunk := range chunks {
+		if chunk.Kind != skip {
+			words = append(words, chunk.Text)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Test: a diff between two drafts always contains both drafts, word for word
+func TestDiffWords_reconstructs(t *testing.T) {
+	reconstructs := func(previous []uint8, current []uint8) bool {
+		// Use a small vocabulary, so that the drafts share words
+		words := func(indexes []uint8) string {
+			vocabulary := []string{"אחת", "שתיים", "שלוש", "ארבע"}
+			result := []string{}
+			for _, index := range indexes {
+				result = append(result, vocabulary[int(index)%len(vocabulary)])
+			}
+			return strings.Join(result, " ")
+		}
+
+		a, b := words(previous), words(current)
+		chunks := diffWords(a, b)
+
+		return joinDiff(chunks, DiffAdded) == a && joinDiff(chunks, DiffRemoved) == b
+	}
+
+	if err := quick.Check(reconstructs, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: identical drafts produce a diff without changes
+func TestDiffWords_identical(t *testing.T) {
+	chunks := diffWords("סיפור סיפורים בקולנוע", "סיפור  סיפורים\nבקולנוע")
+
+	if len(chunks) != 1 || chunks[0].Kind != DiffSame {
+		t.Errorf("expected a single unchanged chunk, got %v", chunks)
+	}
+}
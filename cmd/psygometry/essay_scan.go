@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// The largest photo of a page that is accepted, and the most pages an essay may be photographed in.
+const (
+	maximumScanBytes = 10 << 20
+	maximumScanPages = 4
+)
+
+// The time given to recognizing the text of a single page.
+const recognitionTimeout = time.Minute
+
+// Image types that photos of pages may be uploaded in, as detected from their content.
+var scanContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+	"image/bmp":  true,
+}
+
+// Recognizes the handwritten text in a photo of a page.
+//
+// The Tesseract-backed implementation is used by the server; tests provide stubs so that no recognition is run.
+type textRecognizer interface {
+	Recognize(ctx context.Context, image []byte) (string, error)
+}
+
+var essayRecognizer textRecognizer = tesseractRecognizer{language: "heb"}
+
+// Recognizes text by running the `tesseract` command, which must be installed along with the language data of
+// `language` (e.g. the `tesseract-ocr-heb` package). The command is taken from the `TESSERACT_PATH` environment
+// variable, or looked up in the path when it is empty.
+type tesseractRecognizer struct {
+	language string
+}
+
+func (r tesseractRecognizer) Recognize(ctx context.Context, image []byte) (string, error) {
+	command := os.Getenv("TESSERACT_PATH")
+	if command == "" {
+		command = "tesseract"
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command, "stdin", "stdout", "-l", r.language)
+	cmd.Stdin = bytes.NewReader(image)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tesseract: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+// The text recognized in the photos of an essay, for the student to correct before it is graded.
+type EssayTranscription struct {
+	Session string
+	Writing string
+	Pages   int
+}
+
+var blankLinesRegexp = regexp.MustCompile(`\n{3,}`)
+
+// Tidies the text recognized in a page, removing the spacing around its lines and the runs of blank lines that
+// recognition leaves between them, while keeping the paragraphs apart.
+func cleanRecognizedText(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+
+	text = strings.Join(lines, "\n")
+	return strings.TrimSpace(blankLinesRegexp.ReplaceAllString(text, "\n\n"))
+}
+
+// Recognizes the text in the photos of an essay's pages, in their order.
+func transcribeEssay(ctx context.Context, pages [][]byte) (string, error) {
+	texts := []string{}
+	for _, page := range pages {
+		ctx, cancel := context.WithTimeout(ctx, recognitionTimeout)
+		text, err := essayRecognizer.Recognize(ctx, page)
+		cancel()
+		if err != nil {
+			return "", err
+		}
+
+		if text = cleanRecognizedText(text); text != "" {
+			texts = append(texts, text)
+		}
+	}
+
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// Reads the photos of pages uploaded in the `pages` field, rejecting ones that are too large or are not images.
+func readScannedPages(c echo.Context) ([][]byte, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "missing photos of the essay")
+	}
+
+	headers := form.File["pages"]
+	if len(headers) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "missing photos of the essay")
+	}
+	if len(headers) > maximumScanPages {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("at most %d pages may be uploaded", maximumScanPages))
+	}
+
+	pages := [][]byte{}
+	for _, header := range headers {
+		if header.Size > maximumScanBytes {
+			return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, "photo is too large")
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		page, err := io.ReadAll(io.LimitReader(file, maximumScanBytes))
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if !scanContentTypes[http.DetectContentType(page)] {
+			return nil, echo.NewHTTPError(http.StatusUnsupportedMediaType, "photos must be JPEG, PNG, WebP or BMP images")
+		}
+		pages = append(pages, page)
+	}
+
+	return pages, nil
+}
+
+// Adds the upload of an essay written on paper, as in the real exam. Its photos are transcribed for the student to
+// correct, after which it is submitted to `/essay/drafts` like any other draft.
+func registerEssayScans(e *echo.Echo) {
+	e.POST("/essay/scans", func(c echo.Context) error {
+		session := c.FormValue("session")
+		if _, ok := essayPractices[session]; !ok {
+			return echo.NewHTTPError(http.StatusNotFound, "unknown session")
+		}
+
+		pages, err := readScannedPages(c)
+		if err != nil {
+			return err
+		}
+
+		writing, err := transcribeEssay(c.Request().Context(), pages)
+		if err != nil {
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "text recognition is unavailable")
+		}
+
+		return c.Render(http.StatusOK, "essay-transcription", EssayTranscription{Session: session, Writing: writing, Pages: len(pages)})
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type stubRecognizer struct {
+	pages []string
+	read  int
+}
+
+func (r *stubRecognizer) Recognize(ctx context.Context, image []byte) (string, error) {
+	text := r.pages[r.read]
+	r.read += 1
+	return text, nil
+}
+
+func withRecognizer(t *testing.T, recognizer textRecognizer) {
+	previous := essayRecognizer
+	essayRecognizer = recognizer
+	t.Cleanup(func() { essayRecognizer = previous })
+}
+
+// Uploads the photos to the essay of the session.
+func uploadScans(t *testing.T, session string, photos ...[]byte) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("session", session)
+	for _, photo := range photos {
+		part, err := writer.CreateFormFile("pages", "page.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(photo)
+	}
+	writer.Close()
+
+	e := echo.New()
+	e.Renderer = &Template{templates: template.Must(template.ParseGlob("public/views/*.html"))}
+	registerEssayScans(e)
+
+	request := httptest.NewRequest(http.MethodPost, "/essay/scans", body)
+	request.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// Test: recognized text loses the spacing around its lines and its extra blank lines, but keeps its paragraphs
+func TestCleanRecognizedText(t *testing.T) {
+	text := cleanRecognizedText("  שורה   ראשונה \r\nשורה שנייה\n\n\n\n  פסקה שנייה  \n\n")
+	if expected := "שורה ראשונה\nשורה שנייה\n\nפסקה שנייה"; text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+}
+
+// Test: the text recognized in every page is shown in order for correction, and uploads that are not images are rejected
+func TestEssayScans(t *testing.T) {
+	defer func(original map[string]*EssayPractice) {
+		essayPractices = original
+	}(essayPractices)
+	essayPractices = map[string]*EssayPractice{"session": {Session: "session", Prompt: writingPrompts[0]}}
+
+	recognizer := &stubRecognizer{pages: []string{"עמוד ראשון\n", "  ", "עמוד שלישי"}}
+	withRecognizer(t, recognizer)
+
+	png := []byte("\x89PNG\r\n\x1a\n")
+	recorder := uploadScans(t, "session", png, png, png)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected the photos to be transcribed, got %d: %s", recorder.Code, recorder.Body)
+	}
+	if !strings.Contains(recorder.Body.String(), ">עמוד ראשון\n\nעמוד שלישי</textarea>") {
+		t.Errorf("expected the pages to be transcribed in order, got %s", recorder.Body)
+	}
+	if !strings.Contains(recorder.Body.String(), `hx-post="/essay/drafts"`) {
+		t.Errorf("expected the transcription to be submitted as a draft, got %s", recorder.Body)
+	}
+
+	if recorder := uploadScans(t, "session", []byte("not an image")); recorder.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected a file that is not an image to be rejected, got %d", recorder.Code)
+	}
+	if recorder := uploadScans(t, "unknown", png); recorder.Code != http.StatusNotFound {
+		t.Errorf("expected an unknown session to be rejected, got %d", recorder.Code)
+	}
+	if recognizer.read != 3 {
+		t.Errorf("expected only the accepted photos to be recognized, got %d", recognizer.read)
+	}
+}
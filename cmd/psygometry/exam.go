@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// The step of a psychometry a student is currently at.
+type Phase int
+
+const (
+	// Writing the essay, before any multi-choice section.
+	Writing Phase = iota
+	// Answering the questions of the current section, moving freely between them.
+	Answering
+	// Reviewing the answers of the current section before submitting it.
+	Reviewing
+	// Every section was submitted.
+	Finished
+)
+
+// What a student asks to do next, sent as the `action` form value.
+type Action string
+
+const (
+	// Submits the writing section or the current section, which can never be returned to.
+	Submit Action = "submit"
+	// Moves from answering the current section to its review screen.
+	Review Action = "review"
+	// Moves from the review screen back to answering the current section.
+	Back Action = "back"
+)
+
+var InvalidTransition = errors.New("invalid transition")
+
+type State struct {
+	Phase Phase
+	// Index of the current section, meaningful when answering or reviewing.
+	Page        int
+	Session     string
+	Started     time.Time
+	Psychometry Psychometry
+	// Version of the exam being taken, or empty for the built-in one.
+	ExamVersion string
+	Shuffle     Shuffle
+	// Every form value accepted so far, indexed as displayed.
+	Values url.Values
+}
+
+func newState(session string, psychometry Psychometry) *State {
+	return &State{
+		Phase:       Writing,
+		Session:     session,
+		Started:     time.Now(),
+		Psychometry: psychometry,
+		Shuffle:     shufflePsychometry(sessionRandom(session), psychometry),
+		Values:      url.Values{},
+	}
+}
+
+// The psychometry in the order and with the options shown to the student.
+func (s *State) Displayed() Psychometry {
+	return s.Shuffle.Display(s.Psychometry)
+}
+
+// Moves the state to its next phase, according to the action.
+//
+// Submitting is the only way to leave a section, so sections before the current one are locked.
+func (s *State) Transition(action Action) error {
+	switch {
+	case s.Phase == Writing && action == Submit:
+		s.Page = 0
+		s.Phase = Answering
+	case s.Phase == Answering && action == Review:
+		s.Phase = Reviewing
+	case s.Phase == Reviewing && action == Back:
+		s.Phase = Answering
+	case s.Phase == Reviewing && action == Submit:
+		s.Page += 1
+		s.Phase = Answering
+	default:
+		return InvalidTransition
+	}
+
+	if s.Phase == Answering && s.Page >= len(s.Psychometry.Sections) {
+		s.Phase = Finished
+	}
+	return nil
+}
+
+// Whether a form value can still be changed: the essay only while writing it, and the answers, timings, and flags of
+// a section only while answering it.
+func (s *State) Accepts(key string) bool {
+	path := splitFormKey(key)
+
+	switch path[0] {
+	case "WritingSection":
+		return s.Phase == Writing
+	case "Sections", "Timings", "Flags":
+		return s.Phase == Answering && len(path) > 1 && path[1] == strconv.Itoa(s.Page)
+	default:
+		return false
+	}
+}
+
+// Keeps the form values that are accepted and ignores the rest.
+//
+// Timings are added to the time already spent, since each request only reports the time since the previous one, and
+// answers and flags of the current section are replaced, since unchecked boxes are not sent.
+func (s *State) Merge(form url.Values) {
+	if s.Phase == Answering {
+		for key := range s.Values {
+			if path := splitFormKey(key); path[0] != "Timings" && s.Accepts(key) {
+				s.Values.Del(key)
+			}
+		}
+	}
+
+	for key, value := range form {
+		if !s.Accepts(key) {
+			continue
+		}
+
+		if splitFormKey(key)[0] == "Timings" {
+			previous, _ := strconv.Atoi(s.Values.Get(key))
+			current, err := strconv.Atoi(value[0])
+			if err == nil {
+				s.Values.Set(key, strconv.Itoa(previous+current))
+				continue
+			}
+		}
+
+		s.Values[key] = append([]string{}, value...)
+	}
+}
+
+func (s *State) IsReviewing() bool {
+	return s.Phase == Reviewing
+}
+
+// The current section, while answering or reviewing it.
+func (s *State) Section() Section {
+	return s.Displayed().Sections[s.Page]
+}
+
+// The answers and flags of the current section, to restore them when returning to it.
+func (s *State) SectionValues() map[string][]string {
+	values := map[string][]string{}
+	for key := range s.Values {
+		path := splitFormKey(key)
+		if (path[0] == "Sections" || path[0] == "Flags") && len(path) > 1 && path[1] == strconv.Itoa(s.Page) {
+			values[key] = s.Values[key]
+		}
+	}
+	return values
+}
+
+// A question as shown on the review screen of a section.
+type ReviewedQuestion struct {
+	Number  int
+	Content template.HTML
+	// Describes the chosen option, empty when unanswered.
+	Answer  template.HTML
+	Flagged bool
+}
+
+type SectionReview struct {
+	Section    Section
+	Questions  []ReviewedQuestion
+	Unanswered int
+	Flagged    int
+}
+
+func (s *State) Review() SectionReview {
+	section := s.Section()
+	review := SectionReview{Section: section}
+
+	for j, question := range section.Questions {
+		reviewed := ReviewedQuestion{
+			Number:  j + 1,
+			Content: question.RenderedContent(),
+			Flagged: s.Values.Get(fmt.Sprintf("Flags[%d][%d]", s.Page, j)) != "",
+		}
+
+		key := fmt.Sprintf("Sections[%d][%d]", s.Page, j)
+		option := -1
+		entry := ""
+		switch question.Type {
+		case NumericEntry:
+			entry, _ = parseNumericEntry(s.Values.Get(key))
+		case MultipleSelect:
+			options, _ := parseMultipleSelect(s.Values[key], question)
+			entry = formatOptions(options)
+		default:
+			if parsed, err := strconv.Atoi(s.Values.Get(key)); err == nil {
+				option = parsed
+			}
+		}
+
+		reviewed.Answer = question.DescribeAnswer(option, entry)
+		if reviewed.Answer == "" {
+			review.Unanswered += 1
+		}
+		if reviewed.Flagged {
+			review.Flagged += 1
+		}
+
+		review.Questions = append(review.Questions, reviewed)
+	}
+
+	return review
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A published version of an exam, kept in an exam file named after it.
+//
+// Published versions never change, so that every attempt can be scored against the version that was taken. Fixing an
+// exam publishes a new version, which replaces the earlier ones in the bank.
+type Exam struct {
+	ID          string
+	Version     int
+	Psychometry Psychometry
+}
+
+// Identifies the version, and names its file.
+func (e Exam) VersionID() string {
+	return fmt.Sprintf("%s.v%d", e.ID, e.Version)
+}
+
+// The writing task of the version: its own, or one drawn from the library by the version's ID when it has none, so
+// that every attempt of the version and every booklet printed of it have the same task.
+func (e Exam) WritingPrompt() WritingPrompt {
+	if e.Psychometry.WritingSection.Task != "" {
+		return e.Psychometry.WritingSection
+	}
+
+	// Any prompt matches when neither the genre nor the difficulty are restricted
+	prompt, _ := RandomWritingPrompt(sessionRandom(e.VersionID()), "", 0)
+	return prompt
+}
+
+// Every version of the exams loaded from exam files, ordered by exam and version. The sections of the latest version of
+// each exam are added to the question bank.
+var exams = []Exam{}
+
+// Drafts of exams that are being written, by exam ID. They are not in the bank, and may be invalid.
+var examDrafts = map[string]Psychometry{}
+
+// Path of a directory of exam files, taken from the `EXAMS_PATH` environment variable.
+//
+// Every `.json` file in it holds a single `Psychometry`, with the same field names as the Go types, and is named after
+// the version it holds (e.g. `spring-2024.v2.json`, where a file without a version is the first). Drafts are kept the
+// same way in its `drafts` subdirectory, named after their exam alone. When empty, only the built-in questions are in the bank, and exams
+// written in the authoring pages are lost on restart.
+func examsPath() string {
+	return os.Getenv("EXAMS_PATH")
+}
+
+// Exam IDs are used as file names, so they are limited to characters that are safe in any path.
+var examIDRegexp = regexp.MustCompile("^[A-Za-z0-9_-]+$")
+
+// Loads and validates every exam file in a directory, in the order of their names. Files that are not named after an
+// exam version are skipped with a warning, so that files kept next to the exams do not stop the server.
+func loadExams(dir string) ([]Exam, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	loaded := []Exam{}
+	seen := map[string]string{}
+	for _, path := range paths {
+		id, version, err := parseExamFileName(path)
+		if err != nil {
+			log.Printf("skipping %v", err)
+			continue
+		}
+
+		psychometry, err := loadExamFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		exam := Exam{ID: id, Version: version, Psychometry: psychometry}
+		if other, ok := seen[exam.VersionID()]; ok {
+			return nil, fmt.Errorf("%s: version %s is already in %s", path, exam.VersionID(), other)
+		}
+		seen[exam.VersionID()] = path
+
+		loaded = append(loaded, exam)
+		if err := checkBankIDs(loaded); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(loaded, func(i, j int) bool {
+		if loaded[i].ID != loaded[j].ID {
+			return loaded[i].ID < loaded[j].ID
+		}
+		return loaded[i].Version < loaded[j].Version
+	})
+	return loaded, nil
+}
+
+var examFileNameRegexp = regexp.MustCompile(`^([A-Za-z0-9_-]+)(?:\.v([1-9][0-9]*))?\.json$`)
+
+func parseExamFileName(path string) (string, int, error) {
+	match := examFileNameRegexp.FindStringSubmatch(filepath.Base(path))
+	if match == nil {
+		return "", 0, fmt.Errorf("%s: exam files are named after their exam and version (e.g. exam.v2.json)", path)
+	}
+	if match[2] == "" {
+		return match[1], 1, nil
+	}
+
+	version, err := strconv.Atoi(match[2])
+	if err != nil {
+		return "", 0, fmt.Errorf("%s: %w", path, err)
+	}
+	return match[1], version, nil
+}
+
+// The latest version of every exam, ordered by exam.
+func latestExams() []Exam {
+	latest := []Exam{}
+	for i, exam := range exams {
+		if i+1 == len(exams) || exams[i+1].ID != exam.ID {
+			latest = append(latest, exam)
+		}
+	}
+	return latest
+}
+
+// Finds the latest version of an exam.
+func findExam(id string) (Exam, bool) {
+	found := false
+	latest := Exam{}
+	for _, exam := range exams {
+		if exam.ID == id && exam.Version > latest.Version {
+			latest, found = exam, true
+		}
+	}
+	return latest, found
+}
+
+func findExamVersion(versionID string) (Exam, bool) {
+	for _, exam := range exams {
+		if exam.VersionID() == versionID {
+			return exam, true
+		}
+	}
+	return Exam{}, false
+}
+
+// Loads every draft in a directory of exam files, without validating them.
+func loadExamDrafts(dir string) (map[string]Psychometry, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "drafts", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	drafts := map[string]Psychometry{}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var psychometry Psychometry
+		if err := json.Unmarshal(content, &psychometry); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		drafts[examFileID(path)] = psychometry
+	}
+
+	return drafts, nil
+}
+
+func examFileID(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// Responses refer to questions by ID alone, and questions drawn for practice refer to their passages by ID alone, so
+// both must be unique across the whole bank, although versions of the same exam share them.
+func checkBankIDs(exams []Exam) error {
+	seen := map[string]string{}
+	seenPassages := map[string]string{}
+	for _, exam := range exams {
+		for _, section := range exam.Psychometry.Sections {
+			for _, question := range section.Questions {
+				if other, ok := seen[question.ID]; ok && other != exam.ID {
+					return fmt.Errorf("%s: question %q is already in %s", exam.ID, question.ID, other)
+				}
+				seen[question.ID] = exam.ID
+			}
+			for _, passage := range section.Passages {
+				if other, ok := seenPassages[passage.ID]; ok && other != exam.ID {
+					return fmt.Errorf("%s: passage %q is already in %s", exam.ID, passage.ID, other)
+				}
+				seenPassages[passage.ID] = exam.ID
+			}
+		}
+	}
+	return nil
+}
+
+// Writes an exam file, replacing it at once so that a failed write never leaves half a file behind.
+func writeExamFile(path string, psychometry Psychometry) error {
+	temporary, err := writeTemporaryExamFile(path, psychometry)
+	if err != nil {
+		return err
+	}
+	return os.Rename(temporary, path)
+}
+
+// Writes an exam file that must not exist yet, as published versions are never replaced.
+func createExamFile(path string, psychometry Psychometry) error {
+	temporary, err := writeTemporaryExamFile(path, psychometry)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary)
+
+	// Unlike renaming, linking fails when the file already exists
+	return os.Link(temporary, path)
+}
+
+func writeTemporaryExamFile(path string, psychometry Psychometry) (string, error) {
+	content, err := json.MarshalIndent(psychometry, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+
+	temporary := path + ".tmp"
+	return temporary, os.WriteFile(temporary, content, 0644)
+}
+
+func loadExamFile(path string) (Psychometry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Psychometry{}, err
+	}
+
+	var psychometry Psychometry
+	if err := json.Unmarshal(content, &psychometry); err != nil {
+		return Psychometry{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// Indexes are positions, so they are not written in the file
+	for i := range psychometry.Sections {
+		psychometry.Sections[i].Index = i
+	}
+
+	if err := validateExam(psychometry, assets); err != nil {
+		return Psychometry{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return psychometry, nil
+}
+
+// A problem with an exam, located by the indexes of its section, question and option, each of which is -1 when the
+// problem is not about one.
+type ExamProblem struct {
+	Section  int
+	Question int
+	Option   int
+	Err      error
+}
+
+func (p ExamProblem) Error() string {
+	location := []string{}
+	if p.Section >= 0 {
+		location = append(location, fmt.Sprintf("section %d", p.Section))
+	}
+	if p.Question >= 0 {
+		location = append(location, fmt.Sprintf("question %d", p.Question))
+	}
+	if p.Option >= 0 {
+		location = append(location, fmt.Sprintf("option %d", p.Option))
+	}
+
+	if len(location) == 0 {
+		return p.Err.Error()
+	}
+	return strings.Join(location, ", ") + ": " + p.Err.Error()
+}
+
+func (p ExamProblem) Unwrap() error {
+	return p.Err
+}
+
+// Lists the problems in an error returned while validating an exam, including problems joined together.
+func examProblems(err error) []ExamProblem {
+	if err == nil {
+		return nil
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		problems := []ExamProblem{}
+		for _, err := range joined.Unwrap() {
+			problems = append(problems, examProblems(err)...)
+		}
+		return problems
+	}
+
+	var problem ExamProblem
+	if errors.As(err, &problem) {
+		return []ExamProblem{problem}
+	}
+	return []ExamProblem{{Section: -1, Question: -1, Option: -1, Err: err}}
+}
+
+// Returns every problem with the exam at once, so that they can all be fixed together.
+func validateExam(psychometry Psychometry, assets fs.FS) error {
+	problems := []error{}
+	questionIDs := map[string]bool{}
+	examPassageIDs := map[string]bool{}
+
+	if len(psychometry.Sections) == 0 {
+		problems = append(problems, ExamProblem{-1, -1, -1, errors.New("no sections")})
+	}
+
+	for i, section := range psychometry.Sections {
+		if _, ok := sectionTopics[section.Kind]; !ok {
+			problems = append(problems, ExamProblem{i, -1, -1, fmt.Errorf("unknown kind %q", section.Kind)})
+		}
+		if len(section.Questions) == 0 {
+			problems = append(problems, ExamProblem{i, -1, -1, errors.New("no questions")})
+		}
+
+		passageIDs := map[string]bool{}
+		for _, passage := range section.Passages {
+			if passage.ID == "" {
+				problems = append(problems, ExamProblem{i, -1, -1, errors.New("passage without an ID")})
+			} else if examPassageIDs[passage.ID] {
+				problems = append(problems, ExamProblem{i, -1, -1, fmt.Errorf("duplicate passage %q", passage.ID)})
+			}
+			passageIDs[passage.ID] = true
+			examPassageIDs[passage.ID] = true
+		}
+
+		for j, question := range section.Questions {
+			if question.ID == "" {
+				problems = append(problems, ExamProblem{i, j, -1, errors.New("missing ID")})
+			} else if questionIDs[question.ID] {
+				problems = append(problems, ExamProblem{i, j, -1, fmt.Errorf("duplicate ID %q", question.ID)})
+			}
+			questionIDs[question.ID] = true
+
+			if err := validateItem(question); err != nil {
+				problems = append(problems, ExamProblem{i, j, -1, err})
+			}
+			if question.PassageID != "" && !passageIDs[question.PassageID] {
+				problems = append(problems, ExamProblem{i, j, -1, fmt.Errorf("unknown passage %q", question.PassageID)})
+			}
+			if err := validateText(question.Content); err != nil {
+				problems = append(problems, ExamProblem{i, j, -1, err})
+			}
+			for k, option := range question.Options {
+				if err := validateText(option); err != nil {
+					problems = append(problems, ExamProblem{i, j, k, err})
+				}
+			}
+			if len(question.OptionBlocks) > len(question.Options) {
+				problems = append(problems, ExamProblem{i, j, -1, errors.New("content for missing options")})
+			}
+
+			for _, block := range question.Blocks {
+				if err := validateBlock(block, assets); err != nil {
+					problems = append(problems, ExamProblem{i, j, -1, err})
+				}
+			}
+			for k, blocks := range question.OptionBlocks {
+				for _, block := range blocks {
+					if err := validateBlock(block, assets); err != nil {
+						problems = append(problems, ExamProblem{i, j, k, err})
+					}
+				}
+			}
+		}
+	}
+
+	return errors.Join(problems...)
+}
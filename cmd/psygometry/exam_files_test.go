@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// Test: the built-in exam is valid
+func TestValidateExam_fakeData(t *testing.T) {
+	if err := validateExam(generateFakeData(), os.DirFS("public/assets")); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: every problem with an exam is reported
+func TestValidateExam_problems(t *testing.T) {
+	psychometry := Psychometry{
+		Sections: []Section{
+			{
+				Kind: "X",
+				Questions: []Question{
+					{ID: "a", Options: []string{"1", "2", "3", "4"}, CorrectOption: 4},
+					{ID: "a", Options: []string{"1", "2"}, PassageID: "missing"},
+					{ID: "b", Type: MultipleSelect, Options: []string{"1", "2"}, CorrectOptions: []int{1, 1}},
+					{ID: "c", Type: "essay"},
+				},
+				Passages: []Passage{{ID: "p", Lines: []string{"line"}}},
+			},
+			{
+				Kind:      V,
+				Questions: []Question{{ID: "d", Options: []string{"1", "2"}, PassageID: "p"}},
+				Passages:  []Passage{{ID: "p", Lines: []string{"line"}}},
+			},
+		},
+	}
+
+	err := validateExam(psychometry, fstest.MapFS{})
+	if err == nil {
+		t.Fatal("expected the exam to be invalid")
+	}
+	for _, problem := range []string{"unknown kind", "out of range", "duplicate ID", "unknown passage", "duplicate passage", "duplicate correct option", "unknown question type"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected %q to be reported, got %v", problem, err)
+		}
+	}
+}
+
+// Test: exam files are loaded with their passages, other files are skipped, and questions and passages may not repeat
+// across files
+func TestLoadExams(t *testing.T) {
+	dir := t.TempDir()
+	psychometry := generateFakeData()
+	content, err := json.Marshal(psychometry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.json"), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadExams(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 || len(loaded[0].Psychometry.Sections[1].Passages) != 1 {
+		t.Fatalf("expected the exam to be loaded with its passage, got %v", loaded)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "a copy.json"), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := loadExams(dir); err != nil || len(loaded) != 1 {
+		t.Fatalf("expected a file not named after an exam version to be skipped, got %v (%v)", loaded, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "b.json"), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadExams(dir); err == nil {
+		t.Error("expected repeated questions to be rejected")
+	}
+
+	for i := range psychometry.Sections {
+		for j := range psychometry.Sections[i].Questions {
+			psychometry.Sections[i].Questions[j].ID += "-b"
+		}
+	}
+	content, err = json.Marshal(psychometry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.json"), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadExams(dir); err == nil || !strings.Contains(err.Error(), "passage") {
+		t.Errorf("expected repeated passages to be rejected, got %v", err)
+	}
+}
+
+// Test: the piloted questions are valid, including the assets they refer to
+func TestValidateExam_pilotSections(t *testing.T) {
+	if err := validateExam(Psychometry{Sections: pilotSections}, os.DirFS("public/assets")); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: a version is taken and printed with its own writing task, or with the same task from the library every time
+func TestExamWritingPrompt(t *testing.T) {
+	exam := Exam{ID: "fake", Version: 1, Psychometry: generateFakeData()}
+	if prompt := exam.WritingPrompt(); !reflect.DeepEqual(prompt, exam.Psychometry.WritingSection) {
+		t.Errorf("expected the exam's own task, got %v", prompt)
+	}
+
+	exam.Psychometry.WritingSection = WritingPrompt{}
+	prompt := exam.WritingPrompt()
+	if prompt.Task == "" {
+		t.Fatal("expected a task from the library")
+	}
+	for range 10 {
+		if again := exam.WritingPrompt(); !reflect.DeepEqual(again, prompt) {
+			t.Fatalf("expected the same task every time, got %v and %v", prompt, again)
+		}
+	}
+}
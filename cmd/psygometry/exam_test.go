@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+// Test: only the transitions of the page state machine are allowed, and submitting the last section finishes
+func TestStateTransition(t *testing.T) {
+	state := newState("s", generateFakeData())
+
+	steps := []struct {
+		action Action
+		valid  bool
+		phase  Phase
+		page   int
+	}{
+		{Review, false, Writing, 0},
+		{Submit, true, Answering, 0},
+		{Submit, false, Answering, 0},
+		{Back, false, Answering, 0},
+		{Review, true, Reviewing, 0},
+		{Review, false, Reviewing, 0},
+		{Back, true, Answering, 0},
+		{Review, true, Reviewing, 0},
+		{Submit, true, Answering, 1},
+	}
+
+	for i, step := range steps {
+		err := state.Transition(step.action)
+		if (err == nil) != step.valid {
+			t.Fatalf("step %d: expected valid=%v, got %v", i, step.valid, err)
+		}
+		if state.Phase != step.phase || state.Page != step.page {
+			t.Fatalf("step %d: expected phase %d at page %d, got phase %d at page %d", i, step.phase, step.page, state.Phase, state.Page)
+		}
+	}
+
+	for state.Phase != Finished {
+		if err := state.Transition(Review); err != nil {
+			t.Fatal(err)
+		}
+		if err := state.Transition(Submit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if state.Page != len(state.Psychometry.Sections) {
+		t.Errorf("expected to finish after the last section, finished at page %d", state.Page)
+	}
+	if err := state.Transition(Submit); err != InvalidTransition {
+		t.Errorf("expected a finished psychometry to reject any action, got %v", err)
+	}
+}
+
+// Test: answers to submitted sections and to sections not yet reached are ignored
+func TestStateMerge_locked(t *testing.T) {
+	state := newState("s", generateFakeData())
+	state.Merge(url.Values{"WritingSection": {"essay"}})
+	state.Transition(Submit)
+	state.Merge(url.Values{"WritingSection": {"changed"}, "Sections[0][0]": {"1"}})
+	state.Transition(Review)
+	state.Merge(url.Values{"Sections[0][1]": {"2"}})
+	state.Transition(Submit)
+	state.Merge(url.Values{"Sections[0][0]": {"3"}, "Sections[1][0]": {"0"}, "Sections[2][0]": {"0"}})
+
+	expected := url.Values{"WritingSection": {"essay"}, "Sections[0][0]": {"1"}, "Sections[1][0]": {"0"}}
+	if state.Values.Encode() != expected.Encode() {
+		t.Errorf("expected %v, got %v", expected, state.Values)
+	}
+}
+
+// Test: timings accumulate across visits to a section, while answers and flags reflect the latest submission
+func TestStateMerge_timingsAndFlags(t *testing.T) {
+	state := newState("s", generateFakeData())
+	state.Transition(Submit)
+
+	state.Merge(url.Values{"Timings[0][0]": {"1000"}, "Flags[0][0]": {"on"}, "Sections[0][0]": {"2"}})
+	state.Transition(Review)
+	state.Transition(Back)
+	state.Merge(url.Values{"Timings[0][0]": {"500"}, "Sections[0][0]": {"2"}, "Flags[0][1]": {"on"}})
+
+	if timing := state.Values.Get("Timings[0][0]"); timing != "1500" {
+		t.Errorf("expected 1500 milliseconds, got %s", timing)
+	}
+
+	review := state.Review()
+	if review.Questions[0].Flagged || !review.Questions[1].Flagged {
+		t.Errorf("expected only the second question to be flagged, got %v", review.Questions)
+	}
+	if review.Unanswered != 1 || review.Questions[0].Answer != renderText(state.Section().Questions[0].Options[2]) {
+		t.Errorf("expected only the first question to be answered, got %v", review)
+	}
+}
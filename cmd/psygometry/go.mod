@@ -3,6 +3,8 @@ module github.com/ej-shafran/psygometry
 go 1.22
 
 require (
+	github.com/go-fonts/liberation v0.3.2
+	github.com/go-pdf/fpdf v0.9.0
 	github.com/google/generative-ai-go v0.11.0
 	github.com/labstack/echo/v4 v4.12.0
 	google.golang.org/api v0.176.0
@@ -22,7 +24,7 @@ require (
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/golang/protobuf v1.5.4 // indirect
 	github.com/google/s2a-go v0.1.7 // indirect
-	github.com/google/uuid v1.6.0 // indirect
+	github.com/google/uuid v1.6.0
 	github.com/googleapis/enterprise-certificate-proxy v0.3.2 // indirect
 	github.com/googleapis/gax-go/v2 v2.12.3 // indirect
 	github.com/joho/godotenv v1.5.1 // direct
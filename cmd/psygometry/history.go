@@ -0,0 +1,162 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const studentCookie = "student"
+
+// A single answer given by a student to a question, in any of the practice modes.
+type Response struct {
+	Student string
+	// Identifies the psychometry, drill, or practice session the answer was given in.
+	Attempt    string
+	QuestionID string
+	Kind       SectionKind
+	// The option chosen by the student, or -1 if the question was not answered or is not multiple-choice.
+	Option int
+	// The answer given to a question that is not multiple-choice.
+	Entry   string
+	Correct bool
+	Time    time.Time
+	// Time spent on the question, or zero if it is unknown.
+	Duration time.Duration
+}
+
+var responses = []Response{}
+
+// Path of a JSON lines file that responses are persisted to, taken from the `RESPONSES_PATH` environment variable.
+//
+// When empty, responses are only kept in memory.
+func responsesPath() string {
+	return os.Getenv("RESPONSES_PATH")
+}
+
+// A full psychometry taken by a student, along with its scores.
+type Attempt struct {
+	Student string
+	// Name written on the answer sheet of an attempt taken on paper, which is not a student ID.
+	Name    string
+	Session string
+	// Version of the exam taken, which the attempt is scored against, or empty for the built-in one.
+	ExamVersion string
+	Started     time.Time
+	Finished    time.Time
+	Summary     ScoreSummary
+}
+
+var attempts = []Attempt{}
+
+// Path of a JSON lines file that attempts are persisted to, taken from the `ATTEMPTS_PATH` environment variable.
+//
+// When empty, attempts are only kept in memory.
+func attemptsPath() string {
+	return os.Getenv("ATTEMPTS_PATH")
+}
+
+func recordAttempt(attempt Attempt) error {
+	attempts = append(attempts, attempt)
+
+	if path := attemptsPath(); path != "" {
+		return appendJSONLines(path, []Attempt{attempt})
+	}
+	return nil
+}
+
+// Returns the attempts of a single student, from the earliest to the latest.
+func studentAttempts(student string) []Attempt {
+	result := []Attempt{}
+	for _, attempt := range attempts {
+		if attempt.Student == student {
+			result = append(result, attempt)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Finished.Before(result[j].Finished)
+	})
+	return result
+}
+
+// Identifies the student making the request, assigning them a new ID if they do not have one yet.
+func studentID(c echo.Context) string {
+	cookie, err := c.Cookie(studentCookie)
+	if err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	id := uuid.New().String()
+	c.SetCookie(&http.Cookie{
+		Name:     studentCookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id
+}
+
+// Records every answer in the psychometry. Answers to sections that are not counted are kept as pilot responses.
+func recordResponses(student string, attempt string, psychometry Psychometry, answers PsychometryAnswers) error {
+	now := time.Now()
+	added := []Response{}
+	piloted := []Response{}
+
+	for i, section := range psychometry.Sections {
+		for j, question := range section.Questions {
+			option := answers.Sections[i][j]
+			response := Response{
+				Student:    student,
+				Attempt:    attempt,
+				QuestionID: question.ID,
+				Kind:       section.Kind,
+				Option:     option,
+				Entry:      answers.Entry(i, j),
+				Correct:    question.IsCorrect(option, answers.Entry(i, j)),
+				Time:       now,
+				Duration:   answers.Timing(i, j),
+			}
+
+			if section.IsCounted {
+				added = append(added, response)
+			} else {
+				piloted = append(piloted, response)
+			}
+		}
+	}
+
+	if len(piloted) > 0 {
+		if err := recordPilotResponses(piloted); err != nil {
+			return err
+		}
+	}
+
+	responses = append(responses, added...)
+	for _, response := range added {
+		updateReviewSchedule(response)
+	}
+
+	if path := responsesPath(); path != "" {
+		return appendJSONLines(path, added)
+	}
+	return nil
+}
+
+// Returns the responses of a single student, optionally restricted to a single kind of section (when `kind` is
+// empty, responses of every kind are returned).
+func studentResponses(student string, kind SectionKind) []Response {
+	result := []Response{}
+	for _, response := range responses {
+		if response.Student == student && (kind == "" || response.Kind == kind) {
+			result = append(result, response)
+		}
+	}
+	return result
+}
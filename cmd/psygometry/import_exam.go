@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var InvalidExamText = errors.New("invalid exam text")
+var InvalidAnswerKey = errors.New("invalid answer key")
+
+// Words in the header of a section of a NITE exam that tell its kind.
+var sectionKindWords = map[SectionKind][]string{
+	V: {"מילולית", "verbal"},
+	Q: {"כמותית", "quantitative"},
+	E: {"אנגלית", "english"},
+}
+
+var (
+	sectionHeaderRegexp  = regexp.MustCompile(`(?i)(פרק|section)`)
+	questionNumberRegexp = regexp.MustCompile(`^\s*(\d{1,2})\s*[.)]\s*(.*)$`)
+	optionNumberRegexp   = regexp.MustCompile(`\((\d)\)`)
+	optionLineRegexp     = regexp.MustCompile(`^\(\d\)(\s|$)`)
+)
+
+// Parses the text of a NITE exam, as extracted from its PDF, into its multiple-choice sections.
+//
+// A section starts at a line that names it as a section along with its domain (e.g. "פרק ראשון - חשיבה מילולית"), a
+// question at a line starting with its number (e.g. "1."), and an option at its number in parentheses (e.g. "(1)"),
+// which may share a line with other options. Any other line continues the text before it, except for the instructions
+// before the first question of every section, which are skipped.
+//
+// Only lines that start neither a question nor an option can be headers, so that a question about a "section" of a
+// text is not mistaken for one. A header of the same domain as the current section is the header repeated at the top
+// of every page, and is skipped, unless the questions after it are numbered from 1 again.
+//
+// Numbers must follow each other, and option numbers must stand alone, so that a number within the text of a question
+// is not mistaken for a new question or option. Reading passages and figures are not recognized, and are left within the text they follow.
+func parseExamText(r io.Reader) ([]Section, error) {
+	sections := []Section{}
+	var section *Section
+	var question *Question
+	// Whether the header of the current section was repeated since its last question
+	repeated := false
+
+	startSection := func(kind SectionKind) {
+		sections = append(sections, Section{Kind: kind, Index: len(sections), IsCounted: true})
+		section = &sections[len(sections)-1]
+		question = nil
+		repeated = false
+	}
+
+	// Appends text to the last option of the current question, or to its content before its first option
+	appendText := func(text string) {
+		if text = strings.TrimSpace(text); text == "" || question == nil {
+			return
+		}
+		if len(question.Options) == 0 {
+			question.Content = strings.TrimSpace(question.Content + " " + text)
+		} else {
+			last := len(question.Options) - 1
+			question.Options[last] = strings.TrimSpace(question.Options[last] + " " + text)
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		match := questionNumberRegexp.FindStringSubmatch(line)
+		if match == nil && !optionLineRegexp.MatchString(line) {
+			if kind, ok := sectionHeaderKind(line); ok {
+				if section != nil && section.Kind == kind {
+					repeated = true
+				} else {
+					startSection(kind)
+				}
+				continue
+			}
+		}
+		if section == nil {
+			continue
+		}
+
+		if match != nil && repeated && match[1] == "1" && len(section.Questions) > 0 {
+			startSection(section.Kind)
+		}
+		if match != nil && match[1] == strconv.Itoa(len(section.Questions)+1) {
+			section.Questions = append(section.Questions, Question{CorrectOption: -1})
+			question = &section.Questions[len(section.Questions)-1]
+			line = match[2]
+			repeated = false
+		}
+		if question == nil {
+			continue
+		}
+
+		// Every option number that follows the last one starts a new option, and the text before it continues the last
+		start := 0
+		for _, match := range optionNumberRegexp.FindAllStringSubmatchIndex(line, -1) {
+			if line[match[2]:match[3]] != strconv.Itoa(len(question.Options)+1) || !standsAlone(line, match[0], match[1]) {
+				continue
+			}
+			appendText(line[start:match[0]])
+			question.Options = append(question.Options, "")
+			start = match[1]
+		}
+		appendText(line[start:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("%w: no sections", InvalidExamText)
+	}
+	return sections, nil
+}
+
+// Whether the text between `start` and `end` is a separate word, like option numbers are.
+func standsAlone(line string, start int, end int) bool {
+	return (start == 0 || line[start-1] == ' ') && (end == len(line) || line[end] == ' ')
+}
+
+func sectionHeaderKind(line string) (SectionKind, bool) {
+	if !sectionHeaderRegexp.MatchString(line) {
+		return "", false
+	}
+
+	lower := strings.ToLower(line)
+	for kind, words := range sectionKindWords {
+		for _, word := range words {
+			if strings.Contains(lower, word) {
+				return kind, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Parses an answer key, where every line holds the correct options of a section in order, numbered from 1 as in the
+// booklet, optionally after a label ending with a colon (e.g. "פרק 1: 2 4 1 3").
+func parseAnswerKey(r io.Reader) ([][]int, error) {
+	key := [][]int{}
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line := scanner.Text()
+		if _, after, ok := strings.Cut(line, ":"); ok {
+			line = after
+		}
+
+		answers := []int{}
+		for _, field := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			answer, err := strconv.Atoi(field)
+			if err != nil || answer < 1 {
+				return nil, fmt.Errorf("%w: line %d: %q is not an option number", InvalidAnswerKey, number, field)
+			}
+			answers = append(answers, answer)
+		}
+		if len(answers) > 0 {
+			key = append(key, answers)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// Sets the correct option of every question from the answer key, and names the questions after the exam.
+//
+// Returns the mismatches between the exam and the key, such as sections with a different number of questions than
+// their answers, while still setting every answer that it can.
+func applyAnswerKey(id string, sections []Section, key [][]int) []string {
+	mismatches := []string{}
+	if len(key) != len(sections) {
+		mismatches = append(mismatches, fmt.Sprintf("the exam has %d sections, but the key has %d", len(sections), len(key)))
+	}
+
+	for i := range sections {
+		answers := []int{}
+		if i < len(key) {
+			answers = key[i]
+		}
+		if len(answers) != len(sections[i].Questions) {
+			mismatches = append(mismatches, fmt.Sprintf("section %d has %d questions, but the key has %d answers", i+1, len(sections[i].Questions), len(answers)))
+		}
+
+		for j := range sections[i].Questions {
+			question := &sections[i].Questions[j]
+			question.ID = fmt.Sprintf("%s-%d-%d", id, i+1, j+1)
+			if j >= len(answers) {
+				continue
+			}
+
+			// NITE numbers options from 1
+			if answers[j] > len(question.Options) {
+				mismatches = append(mismatches, fmt.Sprintf("section %d, question %d: answer %d, but only %d options", i+1, j+1, answers[j], len(question.Options)))
+				continue
+			}
+			question.CorrectOption = answers[j] - 1
+		}
+	}
+
+	return mismatches
+}
+
+func importExamCommand(args []string) error {
+	flags := flag.NewFlagSet("import-exam", flag.ContinueOnError)
+	id := flags.String("id", "", "ID of the exam to create as a draft")
+	textInput := flags.String("text", "", "path of the text extracted from the exam")
+	keyInput := flags.String("key", "", "path of the answer key")
+	dir := flags.String("exams", examsPath(), "path of the directory of exam files, where the draft is written")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if !examIDRegexp.MatchString(*id) {
+		return errors.New("missing or invalid exam ID (pass -id, with only English letters, digits, - and _)")
+	}
+	if *textInput == "" || *keyInput == "" {
+		return errors.New("missing exam text or answer key (pass -text and -key)")
+	}
+	if *dir == "" {
+		return errors.New("missing exams path (set EXAMS_PATH or pass -exams)")
+	}
+
+	path := filepath.Join(*dir, "drafts", *id+".json")
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("a draft of %s already exists", *id)
+	}
+
+	text, err := os.Open(*textInput)
+	if err != nil {
+		return err
+	}
+	defer text.Close()
+	sections, err := parseExamText(text)
+	if err != nil {
+		return err
+	}
+
+	keyFile, err := os.Open(*keyInput)
+	if err != nil {
+		return err
+	}
+	defer keyFile.Close()
+	key, err := parseAnswerKey(keyFile)
+	if err != nil {
+		return err
+	}
+
+	mismatches := applyAnswerKey(*id, sections, key)
+	if err := writeExamFile(path, Psychometry{Sections: sections}); err != nil {
+		return err
+	}
+
+	for i, section := range sections {
+		fmt.Printf("section %d (%s): %d questions\n", i+1, section.Kind, len(section.Questions))
+	}
+	for _, mismatch := range mismatches {
+		fmt.Println("mismatch:", mismatch)
+	}
+	fmt.Printf("wrote a draft to %s, to be reviewed and published in /admin/exams/%s\n", path, *id)
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const importedExamText = `
+הוראות כלליות לנבחן
+1. אין לכתוב בטופס
+
+פרק ראשון - חשיבה מילולית
+בפרק זה 2 שאלות.
+1. חם : קר
+(1) גבוה : נמוך (2) ארוך : רחב
+(3) כבד : קל (4) ירוק : צהוב
+2. איזו מהמילים הבאות
+מתארת את שנת 2020 (1)?
+(1) שקט
+(2) סוער
+וגועש
+(3) רגוע (4) שליו
+
+פרק שני - חשיבה כמותית
+1. כמה הם 2 + 3?
+(1) 4 (2) 5 (3) 6
+`
+
+// Test: sections, questions and options are recognized, along with text that continues them
+func TestParseExamText(t *testing.T) {
+	sections, err := parseExamText(strings.NewReader(importedExamText))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sections) != 2 || sections[0].Kind != V || sections[1].Kind != Q || sections[1].Index != 1 {
+		t.Fatalf("expected a verbal and a quantitative section, got %v", sections)
+	}
+
+	questions := sections[0].Questions
+	if len(questions) != 2 {
+		t.Fatalf("expected 2 verbal questions, got %v", questions)
+	}
+	if questions[0].Content != "חם : קר" || !reflect.DeepEqual(questions[0].Options, []string{"גבוה : נמוך", "ארוך : רחב", "כבד : קל", "ירוק : צהוב"}) {
+		t.Errorf("unexpected first question %v", questions[0])
+	}
+	if questions[1].Content != "איזו מהמילים הבאות מתארת את שנת 2020 (1)?" || questions[1].Options[1] != "סוער וגועש" {
+		t.Errorf("unexpected second question %v", questions[1])
+	}
+
+	if len(sections[1].Questions) != 1 || len(sections[1].Questions[0].Options) != 3 {
+		t.Errorf("expected a question with 3 options, got %v", sections[1].Questions)
+	}
+}
+
+const repeatedHeadersText = `
+פרק ראשון - חשיבה מילולית
+1. חם : קר
+(1) גבוה : נמוך (2) ארוך : רחב
+פרק ראשון - חשיבה מילולית
+(3) כבד : קל (4) ירוק : צהוב
+2. עז : חלש
+(1) א (2) ב
+Section 3 - English
+1. Which section of the English text is about verbal reasoning?
+(1) The first (2) The second
+Section 3 - English
+2. What is the main idea?
+(1) Trees (2) Rivers
+Section 4 - English
+1. Choose the best answer.
+(1) Yes (2) No
+`
+
+// Test: headers repeated on every page are skipped unless the questions are numbered from 1 again, and questions that
+// mention a section are not headers
+func TestParseExamText_repeatedHeaders(t *testing.T) {
+	sections, err := parseExamText(strings.NewReader(repeatedHeadersText))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sections) != 3 || sections[0].Kind != V || sections[1].Kind != E || sections[2].Kind != E {
+		t.Fatalf("expected a verbal section and two English sections, got %v", sections)
+	}
+	if len(sections[0].Questions) != 2 || !reflect.DeepEqual(sections[0].Questions[0].Options, []string{"גבוה : נמוך", "ארוך : רחב", "כבד : קל", "ירוק : צהוב"}) {
+		t.Errorf("expected the repeated header to be skipped, got %v", sections[0].Questions)
+	}
+	if len(sections[1].Questions) != 2 || sections[1].Questions[0].Content != "Which section of the English text is about verbal reasoning?" {
+		t.Errorf("expected the question mentioning a section to stay a question, got %v", sections[1].Questions)
+	}
+	if len(sections[2].Questions) != 1 {
+		t.Errorf("expected the numbering from 1 to start a section, got %v", sections[2].Questions)
+	}
+}
+
+// Test: answers numbered from 1 become option indexes, and mismatches with the exam are reported
+func TestApplyAnswerKey(t *testing.T) {
+	sections, err := parseExamText(strings.NewReader(importedExamText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := parseAnswerKey(strings.NewReader("פרק 1: 3, 2\nפרק 2: 2 4\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mismatches := applyAnswerKey("nite", sections, key)
+	if sections[0].Questions[0].CorrectOption != 2 || sections[0].Questions[1].CorrectOption != 1 || sections[1].Questions[0].CorrectOption != 1 {
+		t.Errorf("unexpected correct options %v", sections)
+	}
+	if sections[1].Questions[0].ID != "nite-2-1" {
+		t.Errorf("expected questions to be named after the exam, got %q", sections[1].Questions[0].ID)
+	}
+	if len(mismatches) != 1 || !strings.Contains(mismatches[0], "section 2 has 1 questions") {
+		t.Errorf("expected the second section to be reported, got %v", mismatches)
+	}
+
+	if _, err := parseAnswerKey(strings.NewReader("1 2 x")); err == nil {
+		t.Error("expected an answer that is not a number to be rejected")
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"math"
+)
+
+// Items with fewer responses than this keep the default parameters, rather than being calibrated.
+const minimumCalibrationResponses = 20
+
+// Parameters of a question under the two-parameter logistic (2PL) model of item response theory.
+type ItemParameters struct {
+	// Discrimination: how sharply the probability of a correct answer rises with ability.
+	A float64
+	// Difficulty: the ability at which a correct answer is as likely as an incorrect one.
+	B float64
+}
+
+var defaultItemParameters = ItemParameters{A: 1, B: 0}
+
+// The probability of a student with ability `theta` answering the item correctly.
+func (p ItemParameters) Probability(theta float64) float64 {
+	return 1 / (1 + math.Exp(-p.A*(theta-p.B)))
+}
+
+// The Fisher information the item provides on a student with ability `theta`.
+func (p ItemParameters) Information(theta float64) float64 {
+	probability := p.Probability(theta)
+	return p.A * p.A * probability * (1 - probability)
+}
+
+func clamp(value float64, low float64, high float64) float64 {
+	return math.Max(low, math.Min(value, high))
+}
+
+// The Pearson correlation between a dichotomous variable and a continuous one.
+func pointBiserial(correct []bool, totals []float64) float64 {
+	n := float64(len(correct))
+	if n == 0 {
+		return 0
+	}
+
+	meanTotal := 0.0
+	for _, total := range totals {
+		meanTotal += total
+	}
+	meanTotal /= n
+
+	variance := 0.0
+	for _, total := range totals {
+		variance += (total - meanTotal) * (total - meanTotal)
+	}
+	variance /= n
+	if variance == 0 {
+		return 0
+	}
+
+	correctCount := 0.0
+	correctTotal := 0.0
+	for i, isCorrect := range correct {
+		if isCorrect {
+			correctCount += 1
+			correctTotal += totals[i]
+		}
+	}
+	if correctCount == 0 || correctCount == n {
+		return 0
+	}
+
+	p := correctCount / n
+	meanCorrect := correctTotal / correctCount
+	meanIncorrect := (meanTotal*n - correctTotal) / (n - correctCount)
+
+	return (meanCorrect - meanIncorrect) * math.Sqrt(p*(1-p)) / math.Sqrt(variance)
+}
+
+// The proportion of correct answers of every student, per kind of section.
+func studentProportions(responses []Response) map[SectionKind]map[string]float64 {
+	correct := map[SectionKind]map[string]float64{}
+	total := map[SectionKind]map[string]float64{}
+
+	for _, response := range responses {
+		if correct[response.Kind] == nil {
+			correct[response.Kind] = map[string]float64{}
+			total[response.Kind] = map[string]float64{}
+		}
+		total[response.Kind][response.Student] += 1
+		if response.Correct {
+			correct[response.Kind][response.Student] += 1
+		}
+	}
+
+	for kind, students := range correct {
+		for student := range total[kind] {
+			students[student] /= total[kind][student]
+		}
+	}
+
+	return correct
+}
+
+// Calibrates the parameters of every item from the responses of all students.
+//
+// Uses the classical approximations of the normal-ogive model: the difficulty and discrimination are derived from
+// the proportion of correct answers to the item and its point-biserial correlation with the students' proportion
+// of correct answers in the same domain. Items with too few responses are given `defaultItemParameters`.
+func calibrateItems(responses []Response) map[string]ItemParameters {
+	proportions := studentProportions(responses)
+
+	correct := map[string][]bool{}
+	totals := map[string][]float64{}
+	for _, response := range responses {
+		correct[response.QuestionID] = append(correct[response.QuestionID], response.Correct)
+		totals[response.QuestionID] = append(totals[response.QuestionID], proportions[response.Kind][response.Student])
+	}
+
+	parameters := map[string]ItemParameters{}
+	for id := range correct {
+		if len(correct[id]) < minimumCalibrationResponses {
+			parameters[id] = defaultItemParameters
+			continue
+		}
+
+		correctCount := 0.0
+		for _, isCorrect := range correct[id] {
+			if isCorrect {
+				correctCount += 1
+			}
+		}
+		p := clamp(correctCount/float64(len(correct[id])), 0.02, 0.98)
+		r := clamp(pointBiserial(correct[id], totals[id]), 0.05, 0.9)
+
+		probit := math.Sqrt2 * math.Erfinv(2*p-1)
+		parameters[id] = ItemParameters{
+			// 1.7 scales the normal-ogive discrimination to the logistic model
+			A: clamp(1.7*r/math.Sqrt(1-r*r), 0.2, 3),
+			B: clamp(-probit/r, -4, 4),
+		}
+	}
+
+	return parameters
+}
+
+func itemParameters(parameters map[string]ItemParameters, id string) ItemParameters {
+	if item, ok := parameters[id]; ok {
+		return item
+	}
+	return defaultItemParameters
+}
+
+// An estimate of a student's ability, and the uniform score it corresponds to.
+type AbilityEstimate struct {
+	Theta float64
+	// Standard error of `Theta`.
+	SE float64
+
+	Uniform     int
+	UniformLow  int
+	UniformHigh int
+
+	Answered int
+}
+
+// Estimates ability with the expected a posteriori (EAP) method, using a standard normal prior.
+func estimateAbility(items []ItemParameters, correct []bool) (float64, float64) {
+	const low, high, step = -4.0, 4.0, 0.05
+
+	weightSum := 0.0
+	thetaSum := 0.0
+	thetaSquaredSum := 0.0
+	for theta := low; theta <= high; theta += step {
+		logLikelihood := -theta * theta / 2
+		for i, item := range items {
+			probability := item.Probability(theta)
+			if correct[i] {
+				logLikelihood += math.Log(probability)
+			} else {
+				logLikelihood += math.Log(1 - probability)
+			}
+		}
+
+		weight := math.Exp(logLikelihood)
+		weightSum += weight
+		thetaSum += weight * theta
+		thetaSquaredSum += weight * theta * theta
+	}
+
+	if weightSum == 0 {
+		return 0, 1
+	}
+
+	mean := thetaSum / weightSum
+	variance := thetaSquaredSum/weightSum - mean*mean
+	return mean, math.Sqrt(math.Max(variance, 0))
+}
+
+// Converts an ability to the uniform scale, as the score expected on a section made of all of `bank`.
+func abilityToUniform(theta float64, bank []ItemParameters) int {
+	if len(bank) == 0 {
+		return 50
+	}
+
+	expected := 0.0
+	for _, item := range bank {
+		expected += item.Probability(theta)
+	}
+
+	return 50 + int(math.Round(expected*100/float64(len(bank))))
+}
+
+// Estimates a student's ability in a domain from their responses, with a 95% confidence interval on the uniform
+// scale.
+func estimateStudentAbility(parameters map[string]ItemParameters, bank []ItemParameters, history []Response) AbilityEstimate {
+	items := []ItemParameters{}
+	correct := []bool{}
+	for _, response := range history {
+		items = append(items, itemParameters(parameters, response.QuestionID))
+		correct = append(correct, response.Correct)
+	}
+
+	theta, se := estimateAbility(items, correct)
+
+	return AbilityEstimate{
+		Theta:       theta,
+		SE:          se,
+		Uniform:     abilityToUniform(theta, bank),
+		UniformLow:  abilityToUniform(theta-1.96*se, bank),
+		UniformHigh: abilityToUniform(theta+1.96*se, bank),
+		Answered:    len(history),
+	}
+}
+
+// Selects the candidate that provides the most information on a student with ability `theta`.
+//
+// Returns false if there are no candidates.
+func selectNextItem(parameters map[string]ItemParameters, candidates []string, theta float64) (string, bool) {
+	best := ""
+	bestInformation := -1.0
+
+	for _, id := range candidates {
+		information := itemParameters(parameters, id).Information(theta)
+		if information > bestInformation {
+			best = id
+			bestInformation = information
+		}
+	}
+
+	return best, bestInformation >= 0
+}
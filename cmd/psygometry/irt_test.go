@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+func (ItemParameters) Generate(rand *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(ItemParameters{A: 0.2 + rand.Float64()*2.8, B: rand.Float64()*8 - 4})
+}
+
+// Test: the probability of a correct answer never decreases as ability rises
+func TestItemParameters_monotonic(t *testing.T) {
+	monotonic := func(item ItemParameters, theta float64, delta float64) bool {
+		theta = clamp(theta, -4, 4)
+		delta = clamp(delta, 0, 4)
+		return item.Probability(theta) <= item.Probability(theta+delta)
+	}
+
+	if err := quick.Check(monotonic, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: answering an additional question correctly never lowers the estimated ability
+func TestEstimateAbility_correctRaises(t *testing.T) {
+	raises := func(items []ItemParameters, pattern []bool, extra ItemParameters) bool {
+		correct := make([]bool, len(items))
+		for i := range correct {
+			correct[i] = i < len(pattern) && pattern[i]
+		}
+
+		before, _ := estimateAbility(items, correct)
+		after, _ := estimateAbility(append(items, extra), append(correct, true))
+		return after >= before-1e-9
+	}
+
+	if err := quick.Check(raises, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: estimated uniform scores are always within the uniform scale, and within their own confidence interval
+func TestEstimateStudentAbility_valid(t *testing.T) {
+	valid := func(bank []ItemParameters, pattern []bool) bool {
+		history := []Response{}
+		for i, correct := range pattern {
+			history = append(history, Response{QuestionID: fmt.Sprint(i % 5), Correct: correct})
+		}
+
+		estimate := estimateStudentAbility(map[string]ItemParameters{}, bank, history)
+		inScale := !uniformOutOfBounds(estimate.UniformLow) && !uniformOutOfBounds(estimate.UniformHigh)
+		ordered := estimate.UniformLow <= estimate.Uniform && estimate.Uniform <= estimate.UniformHigh
+		return inScale && ordered
+	}
+
+	if err := quick.Check(valid, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: calibration finds items answered correctly less often to be more difficult
+func TestCalibrateItems_difficulty(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	responses := []Response{}
+	for student := range 200 {
+		ability := random.NormFloat64()
+		for i, difficulty := range []float64{-1.5, 0, 1.5} {
+			correct := random.Float64() < (ItemParameters{A: 1.5, B: difficulty}).Probability(ability)
+			responses = append(responses, Response{
+				Student:    fmt.Sprint(student),
+				QuestionID: fmt.Sprint(i),
+				Kind:       V,
+				Correct:    correct,
+			})
+		}
+	}
+	responses = append(responses, Response{Student: "0", QuestionID: "rare", Kind: V})
+
+	parameters := calibrateItems(responses)
+
+	if !(parameters["0"].B < parameters["1"].B && parameters["1"].B < parameters["2"].B) {
+		t.Errorf("expected difficulties to be ordered, got %v", parameters)
+	}
+	if parameters["rare"] != defaultItemParameters {
+		t.Errorf("expected an item with few responses to keep the default parameters, got %v", parameters["rare"])
+	}
+}
+
+// Test: the selected item is the one closest in difficulty to the ability, when discriminations are equal
+func TestSelectNextItem_closest(t *testing.T) {
+	parameters := map[string]ItemParameters{
+		"easy":   {A: 1, B: -2},
+		"medium": {A: 1, B: 0.5},
+		"hard":   {A: 1, B: 2},
+	}
+
+	id, ok := selectNextItem(parameters, []string{"easy", "medium", "hard"}, 0.3)
+	if !ok || id != "medium" {
+		t.Errorf("expected the medium item to be selected, got %q", id)
+	}
+
+	if _, ok := selectNextItem(parameters, []string{}, 0); ok {
+		t.Error("expected no item to be selected from no candidates")
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Items with fewer responses than this are not flagged, as their statistics are not yet reliable.
+const minimumFlagResponses = 20
+
+// A wrong option is only taken to be the key when its discrimination is above this, as well as above the key's.
+const miskeyedDiscrimination = 0.1
+
+type ItemFlag string
+
+const (
+	// A wrong option correlates positively with the total score, so the key may be wrong.
+	Miskeyed ItemFlag = "miskeyed"
+	TooEasy  ItemFlag = "too-easy"
+	TooHard  ItemFlag = "too-hard"
+	// The item barely separates strong students from weak ones.
+	LowDiscrimination ItemFlag = "low-discrimination"
+)
+
+var itemFlagNames = map[ItemFlag]string{
+	Miskeyed:          "ייתכן שהתשובה הנכונה שגויה",
+	TooEasy:           "קלה מדי",
+	TooHard:           "קשה מדי",
+	LowDiscrimination: "הבחנה נמוכה",
+}
+
+type OptionStatistics struct {
+	// The option's index, or -1 for students who did not answer.
+	Option     int
+	IsKey      bool
+	Count      int
+	Proportion float64
+	// Point-biserial correlation between choosing the option and the rest of the student's score.
+	Discrimination float64
+}
+
+// The option's number as shown to students, counting from 1.
+func (s OptionStatistics) Number() int {
+	return s.Option + 1
+}
+
+// Classical test theory statistics of a single question, over every full attempt that included it.
+type ItemStatistics struct {
+	ID        string
+	Kind      SectionKind
+	Topic     Topic
+	Content   string
+	Responses int
+	// The proportion of correct answers (the item's "p-value").
+	Difficulty float64
+	// Point-biserial correlation between answering correctly and the rest of the student's score.
+	Discrimination float64
+	Options        []OptionStatistics
+	Flags          []ItemFlag
+}
+
+func (s ItemStatistics) HasFlag(flag ItemFlag) bool {
+	for _, f := range s.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// The responses given in full psychometry attempts, leaving out drills, adaptive practice, and reviews, where the
+// rest of the session is too short or too targeted to measure the student by.
+func fullAttemptResponses(responses []Response, attempts []Attempt) []Response {
+	full := map[string]bool{}
+	for _, attempt := range attempts {
+		full[attempt.Session] = true
+	}
+
+	result := []Response{}
+	for _, response := range responses {
+		if full[response.Attempt] {
+			result = append(result, response)
+		}
+	}
+	return result
+}
+
+func attemptKey(response Response) string {
+	return response.Attempt + "/" + string(response.Kind)
+}
+
+// Computes the statistics of every question in `sections` from the responses to it.
+//
+// A response's total score is the number of correct answers in the same domain within the same attempt; each item
+// is correlated with the rest of that score, excluding the item itself.
+func computeItemStatistics(sections []Section, responses []Response) []ItemStatistics {
+	totals := map[string]float64{}
+	byQuestion := map[string][]Response{}
+	for _, response := range responses {
+		if response.Correct {
+			totals[attemptKey(response)] += 1
+		}
+		byQuestion[response.QuestionID] = append(byQuestion[response.QuestionID], response)
+	}
+
+	statistics := []ItemStatistics{}
+	for _, section := range sections {
+		for _, question := range section.Questions {
+			itemResponses := byQuestion[question.ID]
+			item := ItemStatistics{
+				ID:        question.ID,
+				Kind:      section.Kind,
+				Topic:     question.Topic,
+				Content:   question.Content,
+				Responses: len(itemResponses),
+			}
+
+			correct := make([]bool, len(itemResponses))
+			rest := make([]float64, len(itemResponses))
+			correctCount := 0
+			for i, response := range itemResponses {
+				correct[i] = response.Correct
+				rest[i] = totals[attemptKey(response)]
+				if response.Correct {
+					correctCount += 1
+					rest[i] -= 1
+				}
+			}
+			if len(itemResponses) > 0 {
+				item.Difficulty = float64(correctCount) / float64(len(itemResponses))
+			}
+			item.Discrimination = pointBiserial(correct, rest)
+
+			for option := -1; option < len(question.Options); option++ {
+				chosen := make([]bool, len(itemResponses))
+				count := 0
+				for i, response := range itemResponses {
+					chosen[i] = response.Option == option
+					if chosen[i] {
+						count += 1
+					}
+				}
+
+				optionStatistics := OptionStatistics{
+					Option:         option,
+					IsKey:          option == question.CorrectOption,
+					Count:          count,
+					Discrimination: pointBiserial(chosen, rest),
+				}
+				if len(itemResponses) > 0 {
+					optionStatistics.Proportion = float64(count) / float64(len(itemResponses))
+				}
+				item.Options = append(item.Options, optionStatistics)
+			}
+
+			item.Flags = flagItem(item)
+			statistics = append(statistics, item)
+		}
+	}
+
+	return statistics
+}
+
+func flagItem(item ItemStatistics) []ItemFlag {
+	flags := []ItemFlag{}
+	if item.Responses < minimumFlagResponses {
+		return flags
+	}
+
+	for _, option := range item.Options {
+		if option.Option >= 0 && !option.IsKey && option.Count > 0 && option.Discrimination > max(item.Discrimination, miskeyedDiscrimination) {
+			flags = append(flags, Miskeyed)
+			break
+		}
+	}
+	if item.Difficulty > 0.95 {
+		flags = append(flags, TooEasy)
+	}
+	if item.Difficulty < 0.2 {
+		flags = append(flags, TooHard)
+	}
+	if item.Discrimination < 0.2 {
+		flags = append(flags, LowDiscrimination)
+	}
+
+	return flags
+}
+
+// Orders flagged items first, and then the hardest items first.
+func sortItemStatistics(statistics []ItemStatistics) {
+	sort.SliceStable(statistics, func(i, j int) bool {
+		if len(statistics[i].Flags) != len(statistics[j].Flags) {
+			return len(statistics[i].Flags) > len(statistics[j].Flags)
+		}
+		return statistics[i].Difficulty < statistics[j].Difficulty
+	})
+}
+
+func formatStatistic(value float64) string {
+	return strconv.FormatFloat(value, 'f', 3, 64)
+}
+
+func writeItemStatisticsCSV(w io.Writer, statistics []ItemStatistics) error {
+	maxOptions := 0
+	for _, item := range statistics {
+		maxOptions = max(maxOptions, len(item.Options)-1)
+	}
+
+	header := []string{"id", "kind", "topic", "responses", "difficulty", "discrimination", "omitted_proportion", "omitted_discrimination"}
+	for option := range maxOptions {
+		header = append(header, fmt.Sprintf("option_%d_proportion", option+1), fmt.Sprintf("option_%d_discrimination", option+1))
+	}
+	header = append(header, "key", "flags")
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, item := range statistics {
+		record := []string{
+			item.ID,
+			string(item.Kind),
+			string(item.Topic),
+			strconv.Itoa(item.Responses),
+			formatStatistic(item.Difficulty),
+			formatStatistic(item.Discrimination),
+		}
+
+		key := ""
+		for _, option := range item.Options {
+			record = append(record, formatStatistic(option.Proportion), formatStatistic(option.Discrimination))
+			if option.IsKey {
+				key = strconv.Itoa(option.Option + 1)
+			}
+		}
+		for range maxOptions - (len(item.Options) - 1) {
+			record = append(record, "", "")
+		}
+
+		flags := []string{}
+		for _, flag := range item.Flags {
+			flags = append(flags, string(flag))
+		}
+		record = append(record, key, strings.Join(flags, " "))
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// Computes the statistics of every question from the persisted responses, and writes them as CSV.
+func itemStatsCommand(args []string) error {
+	flags := flag.NewFlagSet("item-stats", flag.ContinueOnError)
+	input := flags.String("responses", responsesPath(), "path of the persisted responses")
+	attemptsInput := flags.String("attempts", attemptsPath(), "path of the persisted attempts, whose responses are the only ones counted")
+	pilot := flags.Bool("pilot", false, "compute the statistics of the questions piloted in experimental sections instead")
+	pilotInput := flags.String("pilot-responses", pilotResponsesPath(), "path of the persisted pilot responses")
+	examsInput := flags.String("exams", examsPath(), "path of the directory of exam files, whose questions are counted along with the built-in ones")
+	output := flags.String("o", "", "path to write the CSV to (defaults to standard output)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// The bank starts out with the built-in questions alone, so it is rebuilt once the published exams are loaded
+	if *examsInput != "" {
+		var err error
+		exams, err = loadExams(*examsInput)
+		if err != nil {
+			return err
+		}
+		rebuildBank()
+	}
+
+	if *attemptsInput == "" {
+		return fmt.Errorf("missing attempts path (set ATTEMPTS_PATH or pass -attempts)")
+	}
+	loadedAttempts, err := loadJSONLines[Attempt](*attemptsInput)
+	if err != nil {
+		return err
+	}
+
+	var statistics []ItemStatistics
+	if *pilot {
+		if *pilotInput == "" {
+			return fmt.Errorf("missing pilot responses path (set PILOT_RESPONSES_PATH or pass -pilot-responses)")
+		}
+
+		// Responses to counted sections are optional, but make the discrimination of piloted questions meaningful
+		counted := []Response{}
+		if *input != "" {
+			counted, err = loadJSONLines[Response](*input)
+			if err != nil {
+				return err
+			}
+		}
+
+		piloted, err := loadJSONLines[Response](*pilotInput)
+		if err != nil {
+			return err
+		}
+
+		statistics = pilotItemStatistics(fullAttemptResponses(counted, loadedAttempts), piloted)
+	} else {
+		if *input == "" {
+			return fmt.Errorf("missing responses path (set RESPONSES_PATH or pass -responses)")
+		}
+
+		loaded, err := loadJSONLines[Response](*input)
+		if err != nil {
+			return err
+		}
+
+		statistics = computeItemStatistics(bankSections(), fullAttemptResponses(loaded, loadedAttempts))
+	}
+	sortItemStatistics(statistics)
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	return writeItemStatisticsCSV(w, statistics)
+}
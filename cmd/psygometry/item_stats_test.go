@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// Simulates attempts of students of varying ability on two questions, where strong students choose option
+// `strongOption` of the second question, and weak students choose option `weakOption`.
+func simulateAttempts(strongOption int, weakOption int) ([]Section, []Response) {
+	sections := []Section{{
+		Kind: V,
+		Questions: []Question{
+			{ID: "anchor", Options: make([]string, 4), CorrectOption: 0},
+			{ID: "item", Options: make([]string, 4), CorrectOption: 0},
+		},
+	}}
+
+	random := rand.New(rand.NewSource(1))
+	responses := []Response{}
+	for attempt := range 100 {
+		strong := attempt%2 == 0
+		anchorCorrect := strong == (random.Float64() < 0.9)
+
+		anchorOption := 1
+		if anchorCorrect {
+			anchorOption = 0
+		}
+		itemOption := weakOption
+		if strong {
+			itemOption = strongOption
+		}
+
+		for i, option := range []int{anchorOption, itemOption} {
+			responses = append(responses, Response{
+				Attempt:    fmt.Sprint(attempt),
+				QuestionID: sections[0].Questions[i].ID,
+				Kind:       V,
+				Option:     option,
+				Correct:    option == sections[0].Questions[i].CorrectOption,
+			})
+		}
+	}
+
+	return sections, responses
+}
+
+// Test: an item whose strong students choose a wrong option is flagged as miskeyed
+func TestComputeItemStatistics_miskeyed(t *testing.T) {
+	sections, responses := simulateAttempts(2, 0)
+	statistics := computeItemStatistics(sections, responses)
+
+	if !statistics[1].HasFlag(Miskeyed) {
+		t.Errorf("expected item to be flagged as miskeyed, got %v", statistics[1])
+	}
+}
+
+// Test: an item whose strong students choose the key is not flagged as miskeyed
+func TestComputeItemStatistics_keyed(t *testing.T) {
+	sections, responses := simulateAttempts(0, 3)
+	statistics := computeItemStatistics(sections, responses)
+
+	if statistics[1].HasFlag(Miskeyed) || statistics[1].Discrimination <= 0 {
+		t.Errorf("expected a well-keyed, discriminating item, got %v", statistics[1])
+	}
+	if statistics[1].Difficulty != 0.5 {
+		t.Errorf("expected half of the students to answer correctly, got %f", statistics[1].Difficulty)
+	}
+}
+
+// Test: a wrong option is only taken to be the key when it discriminates clearly, and better than the key
+func TestFlagItem_miskeyed(t *testing.T) {
+	item := func(keyDiscrimination float64, distractorDiscrimination float64) ItemStatistics {
+		return ItemStatistics{
+			Responses:      100,
+			Difficulty:     0.5,
+			Discrimination: keyDiscrimination,
+			Options: []OptionStatistics{
+				{Option: 0, IsKey: true, Count: 50, Discrimination: keyDiscrimination},
+				{Option: 1, Count: 50, Discrimination: distractorDiscrimination},
+			},
+		}
+	}
+
+	cases := []struct {
+		item     ItemStatistics
+		miskeyed bool
+	}{
+		{item(0.4, 0.05), false},
+		{item(0.4, 0.3), false},
+		{item(-0.1, 0.05), false},
+		{item(0.2, 0.5), true},
+	}
+	for _, c := range cases {
+		if flagged := slices.Contains(flagItem(c.item), Miskeyed); flagged != c.miskeyed {
+			t.Errorf("key at %v and distractor at %v: expected miskeyed to be %v", c.item.Discrimination, c.item.Options[1].Discrimination, c.miskeyed)
+		}
+	}
+}
+
+// Test: only responses given in full attempts are counted
+func TestFullAttemptResponses(t *testing.T) {
+	_, responses := simulateAttempts(0, 3)
+	responses = append(responses, Response{Attempt: "drill", QuestionID: "item", Kind: V, Option: 2})
+
+	counted := fullAttemptResponses(responses, []Attempt{{Session: "0"}, {Session: "1"}})
+	if len(counted) != 4 {
+		t.Errorf("expected the 4 responses of the two attempts, got %v", counted)
+	}
+	for _, response := range counted {
+		if response.Attempt != "0" && response.Attempt != "1" {
+			t.Errorf("expected only responses of full attempts, got %v", response)
+		}
+	}
+}
+
+// Test: the CSV export has a header and a row per item
+func TestWriteItemStatisticsCSV(t *testing.T) {
+	sections, responses := simulateAttempts(0, 3)
+	statistics := computeItemStatistics(sections, responses)
+
+	var buffer bytes.Buffer
+	if err := writeItemStatisticsCSV(&buffer, statistics); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buffer).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(statistics)+1 {
+		t.Errorf("expected %d records, got %d", len(statistics)+1, len(records))
+	}
+}
+
+// Test: the command counts the questions of published exams, and not only the built-in ones
+func TestItemStatsCommand_exams(t *testing.T) {
+	defer func(original []Exam) {
+		exams = original
+		rebuildBank()
+	}(exams)
+
+	dir := t.TempDir()
+	psychometry := generateFakeData()
+	for i := range psychometry.Sections {
+		for j := range psychometry.Sections[i].Questions {
+			question := &psychometry.Sections[i].Questions[j]
+			question.ID = fmt.Sprintf("published-%d-%d", i, j)
+			if question.PassageID != "" {
+				question.PassageID += "-published"
+			}
+		}
+		for j := range psychometry.Sections[i].Passages {
+			psychometry.Sections[i].Passages[j].ID += "-published"
+		}
+	}
+	content, err := json.Marshal(psychometry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "published.json"), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	responsesFile := filepath.Join(dir, "responses.jsonl")
+	attemptsFile := filepath.Join(dir, "attempts.jsonl")
+	response := Response{Attempt: "attempt", QuestionID: "published-0-0", Kind: V, Option: 0, Correct: true}
+	if err := appendJSONLines(responsesFile, []Response{response}); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendJSONLines(attemptsFile, []Attempt{{Session: "attempt"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(t.TempDir(), "items.csv")
+	args := []string{"-exams", dir, "-responses", responsesFile, "-attempts", attemptsFile, "-o", output}
+	if err := itemStatsCommand(args); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if record[0] == "published-0-0" {
+			if record[3] != "1" {
+				t.Errorf("expected the response to the published question to be counted, got %v", record)
+			}
+			return
+		}
+	}
+	t.Errorf("expected a row for the published question, got %v", records)
+}
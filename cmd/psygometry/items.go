@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// How a question is answered. Every question of the real test is multiple-choice, but practice material also uses
+// other formats.
+type ItemType string
+
+const (
+	// Choosing a single option, which is checked against `CorrectOption`.
+	MultipleChoice ItemType = ""
+	// Typing a number, which is checked against `NumericAnswer` up to `Tolerance`.
+	NumericEntry ItemType = "numeric"
+	// Choosing any number of options, which are checked against `CorrectOptions`.
+	MultipleSelect ItemType = "multi-select"
+)
+
+var InvalidEntry = errors.New("invalid entry")
+
+func (q Question) IsMultipleChoice() bool { return q.Type == MultipleChoice }
+func (q Question) IsNumericEntry() bool   { return q.Type == NumericEntry }
+func (q Question) IsMultipleSelect() bool { return q.Type == MultipleSelect }
+
+// Checks that the answer of a question fits its type.
+func validateItem(question Question) error {
+	switch question.Type {
+	case MultipleChoice:
+		if len(question.Options) < 2 {
+			return errors.New("fewer than 2 options")
+		}
+		if question.CorrectOption < 0 || question.CorrectOption >= len(question.Options) {
+			return fmt.Errorf("correct option %d out of range", question.CorrectOption)
+		}
+	case NumericEntry:
+		if question.Tolerance < 0 || math.IsNaN(question.Tolerance) {
+			return errors.New("negative tolerance")
+		}
+		if math.IsNaN(question.NumericAnswer) || math.IsInf(question.NumericAnswer, 0) {
+			return errors.New("invalid numeric answer")
+		}
+	case MultipleSelect:
+		if len(question.CorrectOptions) == 0 {
+			return errors.New("no correct options")
+		}
+		seen := map[int]bool{}
+		for _, option := range question.CorrectOptions {
+			if option < 0 || option >= len(question.Options) {
+				return fmt.Errorf("correct option %d out of range", option)
+			}
+			if seen[option] {
+				return fmt.Errorf("duplicate correct option %d", option)
+			}
+			seen[option] = true
+		}
+	default:
+		return fmt.Errorf("unknown question type %q", question.Type)
+	}
+
+	return nil
+}
+
+// Parses a typed number into its canonical form, accepting a decimal comma as well as a decimal point. An empty entry
+// is an unanswered question.
+func parseNumericEntry(raw string) (string, error) {
+	raw = strings.ReplaceAll(strings.TrimSpace(raw), ",", ".")
+	if raw == "" {
+		return "", nil
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return "", InvalidEntry
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64), nil
+}
+
+// Parses the chosen options of a multiple-select question.
+func parseMultipleSelect(values []string, question Question) ([]int, error) {
+	options := []int{}
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+
+		option, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, DeformedIndex
+		}
+		if option < 0 || option >= len(question.Options) {
+			return nil, InvalidIndex
+		}
+		options = append(options, option)
+	}
+	return options, nil
+}
+
+// Writes chosen options as an entry, which is the same for the same options in any order.
+func formatOptions(options []int) string {
+	sorted := append([]int{}, options...)
+	sort.Ints(sorted)
+
+	parts := []string{}
+	for i, option := range sorted {
+		if i > 0 && option == sorted[i-1] {
+			continue
+		}
+		parts = append(parts, strconv.Itoa(option))
+	}
+	return strings.Join(parts, ",")
+}
+
+func parseOptions(entry string) []int {
+	options := []int{}
+	for _, part := range strings.Split(entry, ",") {
+		if option, err := strconv.Atoi(part); err == nil {
+			options = append(options, option)
+		}
+	}
+	return options
+}
+
+// Whether an answer to the question is correct, where `option` is the chosen option of a multiple-choice question and
+// `entry` is the answer to any other type of question.
+func (q Question) IsCorrect(option int, entry string) bool {
+	switch q.Type {
+	case NumericEntry:
+		value, err := strconv.ParseFloat(entry, 64)
+		return err == nil && math.Abs(value-q.NumericAnswer) <= q.Tolerance
+	case MultipleSelect:
+		return entry != "" && entry == formatOptions(q.CorrectOptions)
+	default:
+		return option >= 0 && option == q.CorrectOption
+	}
+}
+
+// Describes an answer to the question, or returns an empty string when it was not answered.
+func (q Question) DescribeAnswer(option int, entry string) template.HTML {
+	switch q.Type {
+	case NumericEntry:
+		return template.HTML(html.EscapeString(entry))
+	case MultipleSelect:
+		if entry == "" {
+			return ""
+		}
+		return q.describeOptions(parseOptions(entry))
+	default:
+		if option < 0 || option >= len(q.Options) {
+			return ""
+		}
+		return q.describeOptions([]int{option})
+	}
+}
+
+func (q Question) CorrectAnswer() template.HTML {
+	switch q.Type {
+	case NumericEntry:
+		return template.HTML(strconv.FormatFloat(q.NumericAnswer, 'g', -1, 64))
+	case MultipleSelect:
+		return q.describeOptions(q.CorrectOptions)
+	default:
+		return q.describeOptions([]int{q.CorrectOption})
+	}
+}
+
+// Options may be made only of rich content, in which case they are referred to by their number.
+func (q Question) describeOptions(options []int) template.HTML {
+	parts := []string{}
+	for _, option := range options {
+		if option < 0 || option >= len(q.Options) {
+			continue
+		}
+
+		text := string(renderText(q.Options[option]))
+		if text == "" {
+			text = "תשובה " + strconv.Itoa(option+1)
+		}
+		parts = append(parts, text)
+	}
+	return template.HTML(strings.Join(parts, ", "))
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"testing/quick"
+)
+
+// Test: typed numbers are parsed into the same canonical form however they are written
+func TestParseNumericEntry(t *testing.T) {
+	cases := []struct {
+		raw      string
+		expected string
+		err      error
+	}{
+		{"", "", nil},
+		{"  ", "", nil},
+		{"50", "50", nil},
+		{" 050 ", "50", nil},
+		{"0.5", "0.5", nil},
+		{"0,5", "0.5", nil},
+		{"-2.50", "-2.5", nil},
+		{"abc", "", InvalidEntry},
+		{"NaN", "", InvalidEntry},
+		{"Inf", "", InvalidEntry},
+	}
+
+	for _, c := range cases {
+		entry, err := parseNumericEntry(c.raw)
+		if entry != c.expected || err != c.err {
+			t.Errorf("%q: expected %q (%v), got %q (%v)", c.raw, c.expected, c.err, entry, err)
+		}
+	}
+}
+
+// Test: chosen options are written the same way in any order, and read back as they were chosen
+func TestFormatOptions(t *testing.T) {
+	f := func(options []uint8) bool {
+		chosen := []int{}
+		reversed := []int{}
+		for _, option := range options {
+			chosen = append(chosen, int(option))
+			reversed = append([]int{int(option)}, reversed...)
+		}
+
+		entry := formatOptions(chosen)
+		if entry != formatOptions(reversed) {
+			return false
+		}
+
+		for _, option := range chosen {
+			found := false
+			for _, parsed := range parseOptions(entry) {
+				found = found || parsed == option
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: answers are checked by the type of their question
+func TestQuestionIsCorrect(t *testing.T) {
+	choice := Question{Options: []string{"a", "b", "c"}, CorrectOption: 2}
+	numeric := Question{Type: NumericEntry, NumericAnswer: 2.5, Tolerance: 0.01}
+	multiple := Question{Type: MultipleSelect, Options: []string{"a", "b", "c", "d", "e"}, CorrectOptions: []int{4, 0}}
+
+	cases := []struct {
+		question Question
+		option   int
+		entry    string
+		expected bool
+	}{
+		{choice, 2, "", true},
+		{choice, 1, "", false},
+		{choice, -1, "", false},
+		{numeric, -1, "2.5", true},
+		{numeric, -1, "2.505", true},
+		{numeric, -1, "2.6", false},
+		{numeric, -1, "", false},
+		{multiple, -1, "0,4", true},
+		{multiple, -1, "0", false},
+		{multiple, -1, "0,1,4", false},
+		{multiple, -1, "", false},
+	}
+
+	for i, c := range cases {
+		if correct := c.question.IsCorrect(c.option, c.entry); correct != c.expected {
+			t.Errorf("case %d: expected %v, got %v", i, c.expected, correct)
+		}
+	}
+}
+
+// Test: questions of every type are parsed and scored alongside each other
+func TestParsePsychometryAnswers_itemTypes(t *testing.T) {
+	psychometry := Psychometry{
+		Sections: []Section{
+			{
+				Kind:      Q,
+				IsCounted: true,
+				Questions: []Question{
+					{Options: []string{"a", "b", "c", "d", "e", "f"}, CorrectOption: 5},
+					{Type: NumericEntry, NumericAnswer: 0.5},
+					{Type: MultipleSelect, Options: []string{"a", "b", "c"}, CorrectOptions: []int{0, 2}},
+				},
+			},
+		},
+	}
+
+	form := url.Values{
+		"Sections[0][0]": {"5"},
+		"Sections[0][1]": {"0,5"},
+		"Sections[0][2]": {"2", "0"},
+	}
+	answers, err := ParsePsychometryAnswers(form, psychometry, Shuffle{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if answers.Sections[0][0] != 5 || answers.Entry(0, 1) != "0.5" || answers.Entry(0, 2) != "0,2" {
+		t.Errorf("unexpected answers %v, %v", answers.Sections, answers.Entries)
+	}
+	if raw := rawCategoryScore(psychometry.Sections, answers.Sections, answers.Entries); raw != 3 {
+		t.Errorf("expected all 3 answers to be correct, got %d", raw)
+	}
+
+	for key, value := range map[string]string{"Sections[0][1]": "five", "Sections[0][2]": "3"} {
+		invalid := url.Values{key: {value}}
+		if _, err := ParsePsychometryAnswers(invalid, psychometry, Shuffle{}); err == nil {
+			t.Errorf("expected %s=%s to be rejected", key, value)
+		}
+	}
+}
+
+// Test: displayed options of a multiple-select question are mapped back to the options they were shown as
+func TestParsePsychometryAnswers_shuffledMultipleSelect(t *testing.T) {
+	f := func(session string) bool {
+		psychometry := Psychometry{
+			Sections: []Section{
+				{
+					Kind:      V,
+					IsCounted: true,
+					Questions: []Question{
+						{Type: MultipleSelect, Options: []string{"a", "b", "c", "d"}, CorrectOptions: []int{1, 3}},
+					},
+				},
+			},
+		}
+
+		shuffle := shufflePsychometry(sessionRandom(session), psychometry)
+		displayed := shuffle.Display(psychometry).Sections[0].Questions[0]
+
+		form := url.Values{}
+		for _, option := range displayed.CorrectOptions {
+			form.Add("Sections[0][0]", strconv.Itoa(option))
+		}
+
+		answers, err := ParsePsychometryAnswers(form, psychometry, shuffle)
+		return err == nil && psychometry.Sections[0].Questions[0].IsCorrect(-1, answers.Entry(0, 0))
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Test: persisted responses are loaded back as they were recorded
+func TestLoadJSONLines_roundTrip(t *testing.T) {
+	_, recorded := simulateAttempts(0, 3)
+	path := filepath.Join(t.TempDir(), "responses.jsonl")
+
+	if err := appendJSONLines(path, recorded[:10]); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendJSONLines(path, recorded[10:]); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadJSONLines[Response](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, recorded) {
+		t.Error("loaded responses differ from recorded responses")
+	}
+}
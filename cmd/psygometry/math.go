@@ -0,0 +1,374 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+	"unicode"
+)
+
+// Math is written in a subset of LaTeX, inline between `$` signs (`\$` for a literal dollar sign) or as a math block,
+// and rendered to MathML on the server so that no script is needed to show it.
+//
+// The subset covers what the quantitative sections use: numbers, variables, operators and relations, `^` and `_`,
+// `\frac`, `\sqrt` (with an optional degree), `\overline`, `\text`, and common symbols such as `\pi`, `\le`, and
+// `\angle`.
+
+var UnclosedMath = errors.New("unclosed math")
+
+type UnsupportedMath struct {
+	Source string
+}
+
+func (e UnsupportedMath) Error() string {
+	return fmt.Sprintf("unsupported math: %s", e.Source)
+}
+
+// Commands that stand for a single symbol, and the MathML element that shows it.
+var mathSymbols = map[string]string{
+	"pi":       "<mi>π</mi>",
+	"alpha":    "<mi>α</mi>",
+	"beta":     "<mi>β</mi>",
+	"gamma":    "<mi>γ</mi>",
+	"theta":    "<mi>θ</mi>",
+	"infty":    "<mi>∞</mi>",
+	"le":       "<mo>≤</mo>",
+	"leq":      "<mo>≤</mo>",
+	"ge":       "<mo>≥</mo>",
+	"geq":      "<mo>≥</mo>",
+	"neq":      "<mo>≠</mo>",
+	"approx":   "<mo>≈</mo>",
+	"cdot":     "<mo>⋅</mo>",
+	"times":    "<mo>×</mo>",
+	"div":      "<mo>÷</mo>",
+	"pm":       "<mo>±</mo>",
+	"angle":    "<mo>∠</mo>",
+	"triangle": "<mo>△</mo>",
+	"perp":     "<mo>⊥</mo>",
+	"parallel": "<mo>∥</mo>",
+	"circ":     "<mo>°</mo>",
+	"%":        "<mo>%</mo>",
+	"$":        "<mo>$</mo>",
+	"{":        "<mo>{</mo>",
+	"}":        "<mo>}</mo>",
+	",":        `<mspace width="0.17em"/>`,
+	";":        `<mspace width="0.28em"/>`,
+}
+
+const mathOperators = "+-=<>()[]|,./!':;*"
+
+type mathParser struct {
+	source []rune
+	pos    int
+}
+
+// Renders math markup to MathML. The math is always laid out left to right, and isolated from the text around it, so
+// that it reads correctly inside right to left Hebrew text.
+func renderMath(source string, block bool) (template.HTML, error) {
+	parser := mathParser{source: []rune(source)}
+	row, err := parser.parseRow(0)
+	if err != nil {
+		return "", err
+	}
+
+	display := "inline"
+	if block {
+		display = "block"
+	}
+	return template.HTML(fmt.Sprintf(`<bdi dir="ltr"><math display="%s" dir="ltr">%s</math></bdi>`, display, row)), nil
+}
+
+func (p *mathParser) peek() (rune, bool) {
+	for p.pos < len(p.source) && unicode.IsSpace(p.source[p.pos]) {
+		p.pos += 1
+	}
+	if p.pos >= len(p.source) {
+		return 0, false
+	}
+	return p.source[p.pos], true
+}
+
+// Parses atoms and their scripts until the closing rune (or the end of the source, when it is 0).
+func (p *mathParser) parseRow(closing rune) (string, error) {
+	items := []string{}
+
+	for {
+		r, ok := p.peek()
+		if !ok {
+			if closing != 0 {
+				return "", UnclosedMath
+			}
+			break
+		}
+		if closing != 0 && r == closing {
+			p.pos += 1
+			break
+		}
+		if r == '}' {
+			return "", UnsupportedMath{string(p.source)}
+		}
+
+		if r == '^' || r == '_' {
+			if len(items) == 0 {
+				return "", UnsupportedMath{string(p.source)}
+			}
+			scripted, err := p.parseScripts(items[len(items)-1])
+			if err != nil {
+				return "", err
+			}
+			items[len(items)-1] = scripted
+			continue
+		}
+
+		atom, err := p.parseAtom()
+		if err != nil {
+			return "", err
+		}
+		items = append(items, atom)
+	}
+
+	if len(items) == 1 {
+		return items[0], nil
+	}
+	return "<mrow>" + strings.Join(items, "") + "</mrow>", nil
+}
+
+// Parses the superscript and subscript that follow a base, in either order.
+func (p *mathParser) parseScripts(base string) (string, error) {
+	var sub, sup string
+	for {
+		r, ok := p.peek()
+		if !ok || (r != '^' && r != '_') {
+			break
+		}
+		p.pos += 1
+
+		script, err := p.parseAtom()
+		if err != nil {
+			return "", err
+		}
+		if r == '^' {
+			if sup != "" {
+				return "", UnsupportedMath{string(p.source)}
+			}
+			sup = script
+		} else {
+			if sub != "" {
+				return "", UnsupportedMath{string(p.source)}
+			}
+			sub = script
+		}
+	}
+
+	switch {
+	case sub != "" && sup != "":
+		return "<msubsup>" + base + sub + sup + "</msubsup>", nil
+	case sup != "":
+		return "<msup>" + base + sup + "</msup>", nil
+	default:
+		return "<msub>" + base + sub + "</msub>", nil
+	}
+}
+
+func (p *mathParser) parseAtom() (string, error) {
+	r, ok := p.peek()
+	if !ok {
+		return "", UnclosedMath
+	}
+
+	switch {
+	case r == '{':
+		p.pos += 1
+		row, err := p.parseRow('}')
+		if err != nil {
+			return "", err
+		}
+		return "<mrow>" + row + "</mrow>", nil
+	case unicode.IsDigit(r):
+		start := p.pos
+		for p.pos < len(p.source) && (unicode.IsDigit(p.source[p.pos]) || p.source[p.pos] == '.') {
+			p.pos += 1
+		}
+		return "<mn>" + html.EscapeString(string(p.source[start:p.pos])) + "</mn>", nil
+	case r < unicode.MaxASCII && unicode.IsLetter(r):
+		p.pos += 1
+		return "<mi>" + string(r) + "</mi>", nil
+	case r == '-':
+		p.pos += 1
+		return "<mo>−</mo>", nil
+	case strings.ContainsRune(mathOperators, r):
+		p.pos += 1
+		return "<mo>" + html.EscapeString(string(r)) + "</mo>", nil
+	case r == '\\':
+		p.pos += 1
+		return p.parseCommand()
+	default:
+		return "", UnsupportedMath{string(p.source)}
+	}
+}
+
+func (p *mathParser) parseCommand() (string, error) {
+	start := p.pos
+	for p.pos < len(p.source) && p.source[p.pos] < unicode.MaxASCII && unicode.IsLetter(p.source[p.pos]) {
+		p.pos += 1
+	}
+	// Commands made of a single symbol, such as `\%`
+	if p.pos == start && p.pos < len(p.source) {
+		p.pos += 1
+	}
+	name := string(p.source[start:p.pos])
+
+	if symbol, ok := mathSymbols[name]; ok {
+		return symbol, nil
+	}
+
+	switch name {
+	case "frac":
+		numerator, err := p.parseAtom()
+		if err != nil {
+			return "", err
+		}
+		denominator, err := p.parseAtom()
+		if err != nil {
+			return "", err
+		}
+		return "<mfrac>" + numerator + denominator + "</mfrac>", nil
+	case "sqrt":
+		if r, ok := p.peek(); ok && r == '[' {
+			p.pos += 1
+			degree, err := p.parseRow(']')
+			if err != nil {
+				return "", err
+			}
+			radicand, err := p.parseAtom()
+			if err != nil {
+				return "", err
+			}
+			return "<mroot>" + radicand + degree + "</mroot>", nil
+		}
+		radicand, err := p.parseAtom()
+		if err != nil {
+			return "", err
+		}
+		return "<msqrt>" + radicand + "</msqrt>", nil
+	case "overline":
+		base, err := p.parseAtom()
+		if err != nil {
+			return "", err
+		}
+		return `<mover accent="true">` + base + "<mo>‾</mo></mover>", nil
+	case "text":
+		if r, ok := p.peek(); !ok || r != '{' {
+			return "", UnsupportedMath{string(p.source)}
+		}
+		p.pos += 1
+		end := p.pos
+		for end < len(p.source) && p.source[end] != '}' {
+			end += 1
+		}
+		if end >= len(p.source) {
+			return "", UnclosedMath
+		}
+		text := string(p.source[p.pos:end])
+		p.pos = end + 1
+		return "<mtext>" + html.EscapeString(text) + "</mtext>", nil
+	case "left", "right":
+		// Delimiters stretch on their own in MathML
+		return p.parseAtom()
+	default:
+		return "", UnsupportedMath{"\\" + name}
+	}
+}
+
+// Splits text into plain text and the inline math within it, alternating and starting with plain text.
+func splitInlineMath(text string) ([]string, error) {
+	segments := []string{}
+	var current strings.Builder
+	inMath := false
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == '$' && !inMath:
+			current.WriteRune('$')
+			i += 1
+		case runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == '$':
+			current.WriteString(`\$`)
+			i += 1
+		case runes[i] == '$':
+			segments = append(segments, current.String())
+			current.Reset()
+			inMath = !inMath
+		default:
+			current.WriteRune(runes[i])
+		}
+	}
+	if inMath {
+		return nil, UnclosedMath
+	}
+
+	return append(segments, current.String()), nil
+}
+
+// Renders text with inline math. Math that cannot be rendered is shown as its source, so that a mistake in a single
+// question does not break the page (exam files are validated when they are loaded, so this should not happen).
+func renderText(text string) template.HTML {
+	segments, err := splitInlineMath(text)
+	if err != nil {
+		return template.HTML(html.EscapeString(text))
+	}
+
+	var result strings.Builder
+	for i, segment := range segments {
+		if i%2 == 0 {
+			result.WriteString(html.EscapeString(segment))
+			continue
+		}
+
+		math, err := renderMath(segment, false)
+		if err != nil {
+			result.WriteString(`<code dir="ltr">` + html.EscapeString(segment) + "</code>")
+			continue
+		}
+		result.WriteString(string(math))
+	}
+
+	return template.HTML(result.String())
+}
+
+// Checks that every bit of inline math in the text can be rendered.
+func validateText(text string) error {
+	segments, err := splitInlineMath(text)
+	if err != nil {
+		return err
+	}
+
+	for i := 1; i < len(segments); i += 2 {
+		if _, err := renderMath(segments[i], false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q Question) RenderedContent() template.HTML {
+	return renderText(q.Content)
+}
+
+func (o OptionContent) RenderedText() template.HTML {
+	return renderText(o.Text)
+}
+
+func (b Block) RenderedText() template.HTML {
+	return renderText(b.Text)
+}
+
+func (b Block) RenderedMath() template.HTML {
+	math, err := renderMath(b.Text, true)
+	if err != nil {
+		return template.HTML(`<code dir="ltr">` + html.EscapeString(b.Text) + "</code>")
+	}
+	return math
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test: the supported markup is rendered to the matching MathML elements
+func TestRenderMath(t *testing.T) {
+	cases := map[string]string{
+		`x^2`:            "<msup><mi>x</mi><mn>2</mn></msup>",
+		`a_1^{n+1}`:      "<msubsup><mi>a</mi><mn>1</mn><mrow><mrow><mi>n</mi><mo>+</mo><mn>1</mn></mrow></mrow></msubsup>",
+		`\frac{1}{2}`:    "<mfrac><mrow><mn>1</mn></mrow><mrow><mn>2</mn></mrow></mfrac>",
+		`\sqrt{x}`:       "<msqrt><mrow><mi>x</mi></mrow></msqrt>",
+		`\sqrt[3]{8}`:    "<mroot><mrow><mn>8</mn></mrow><mn>3</mn></mroot>",
+		`x \le -3.5`:     "<mrow><mi>x</mi><mo>≤</mo><mo>−</mo><mn>3.5</mn></mrow>",
+		`\angle ABC`:     "<mrow><mo>∠</mo><mi>A</mi><mi>B</mi><mi>C</mi></mrow>",
+		`\overline{AB}`:  `<mover accent="true"><mrow><mrow><mi>A</mi><mi>B</mi></mrow></mrow><mo>‾</mo></mover>`,
+		`\text{<b>}`:     "<mtext>&lt;b&gt;</mtext>",
+		`[0, 1)`:         "<mrow><mo>[</mo><mn>0</mn><mo>,</mo><mn>1</mn><mo>)</mo></mrow>",
+		`50\%`:           "<mrow><mn>50</mn><mo>%</mo></mrow>",
+		`\left(a\right)`: "<mrow><mo>(</mo><mi>a</mi><mo>)</mo></mrow>",
+	}
+
+	for source, expected := range cases {
+		math, err := renderMath(source, false)
+		if err != nil {
+			t.Errorf("%s: %v", source, err)
+			continue
+		}
+		if !strings.Contains(string(math), `dir="ltr"`) || !strings.Contains(string(math), expected) {
+			t.Errorf("%s: expected %s, got %s", source, expected, math)
+		}
+	}
+}
+
+// Test: unbalanced or unsupported markup is rejected
+func TestRenderMath_invalid(t *testing.T) {
+	for _, source := range []string{`\frac{1}`, `{x`, `x}`, `^2`, `x^2^3`, `\unknown`, `\text{x`, `א`} {
+		if _, err := renderMath(source, false); err == nil {
+			t.Errorf("%s: expected an error", source)
+		}
+	}
+
+	// Operators are escaped rather than rejected
+	math, err := renderMath(`<`, false)
+	if err != nil || !strings.Contains(string(math), "<mo>&lt;</mo>") {
+		t.Errorf("expected an escaped operator, got %s (%v)", math, err)
+	}
+}
+
+// Test: inline math is rendered within escaped text, and escaped dollar signs are kept as text
+func TestRenderText(t *testing.T) {
+	rendered := string(renderText(`מחיר של \$5 <b> הוא $x^2$`))
+
+	if !strings.Contains(rendered, "מחיר של $5 &lt;b&gt; הוא ") {
+		t.Errorf("expected escaped text with a literal dollar sign, got %s", rendered)
+	}
+	if !strings.Contains(rendered, "<msup><mi>x</mi><mn>2</mn></msup>") {
+		t.Errorf("expected rendered math, got %s", rendered)
+	}
+
+	if rendered := string(renderText(`$\unknown$ <i>`)); !strings.Contains(rendered, `<code dir="ltr">\unknown</code> &lt;i&gt;`) {
+		t.Errorf("expected invalid math to be shown as its source, got %s", rendered)
+	}
+	if err := validateText(`$x`); err != UnclosedMath {
+		t.Errorf("expected unclosed math to be invalid, got %v", err)
+	}
+}
@@ -8,13 +8,16 @@ import (
 	"reflect"
 	"testing"
 	"testing/quick"
+	"time"
 )
 
 func makeSection(rand *rand.Rand, size int) Section {
 	// At minimum, each section must have one answer
 	questions := make([]Question, size+1)
 	for i := range questions {
-		questions[i] = Question{CorrectOption: rand.Intn(3)}
+		// Between 2 and 4 options, like the questions of the real test and of practice material
+		options := make([]string, 2+rand.Intn(3))
+		questions[i] = Question{Options: options, CorrectOption: rand.Intn(len(options))}
 	}
 
 	kinds := []SectionKind{V, Q, E}
@@ -35,7 +38,7 @@ func makeSectionArray(rand *rand.Rand, size int) []Section {
 
 func (Psychometry) Generate(rand *rand.Rand, size int) reflect.Value {
 	psychometry := Psychometry{
-		WritingSection: "",
+		WritingSection: WritingPrompt{},
 		Sections:       makeSectionArray(rand, size),
 	}
 	return reflect.ValueOf(psychometry)
@@ -78,7 +81,7 @@ func (successValues) Generate(rand *rand.Rand, size int) reflect.Value {
 
 func TestParsePsychometryAnswers_success(t *testing.T) {
 	success := func(psychometry Psychometry, form successValues) bool {
-		a, err := ParsePsychometryAnswers(url.Values(form), psychometry)
+		a, err := ParsePsychometryAnswers(url.Values(form), psychometry, Shuffle{})
 		return err == nil && a.WritingSection == url.Values(form).Get("WritingSection")
 	}
 
@@ -103,7 +106,7 @@ func (missingIndexValues) Generate(rand *rand.Rand, size int) reflect.Value {
 
 func TestParsePsychometryAnswers_missingIndex(t *testing.T) {
 	missingIndex := func(psychometry Psychometry, form missingIndexValues) bool {
-		_, err := ParsePsychometryAnswers(url.Values(form), psychometry)
+		_, err := ParsePsychometryAnswers(url.Values(form), psychometry, Shuffle{})
 		return err == MissingIndex
 	}
 
@@ -128,7 +131,7 @@ func (deformedIndexValues) Generate(rand *rand.Rand, size int) reflect.Value {
 
 func TestParsePsychometryAnswers_deformedIndex(t *testing.T) {
 	deformedIndex := func(psychometry Psychometry, form deformedIndexValues) bool {
-		_, err := ParsePsychometryAnswers(url.Values(form), psychometry)
+		_, err := ParsePsychometryAnswers(url.Values(form), psychometry, Shuffle{})
 		return err == DeformedIndex
 	}
 
@@ -158,7 +161,7 @@ func (invalidIndexValues) Generate(rand *rand.Rand, size int) reflect.Value {
 
 func TestParsePsychometryAnswers_invalidIndex(t *testing.T) {
 	invalidIndex := func(psychometry Psychometry, form invalidIndexValues) bool {
-		_, err := ParsePsychometryAnswers(url.Values(form), psychometry)
+		_, err := ParsePsychometryAnswers(url.Values(form), psychometry, Shuffle{})
 		return err == InvalidIndex
 	}
 
@@ -166,3 +169,41 @@ func TestParsePsychometryAnswers_invalidIndex(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// Test: parsing an answer form with timings stores them alongside the answers
+func TestParsePsychometryAnswers_timings(t *testing.T) {
+	timings := func(psychometry Psychometry, sIndex uint, qIndex uint, milliseconds uint32) bool {
+		section := int(sIndex % uint(len(psychometry.Sections)))
+		question := int(qIndex % uint(len(psychometry.Sections[section].Questions)))
+
+		form := url.Values{}
+		form.Add(fmt.Sprintf("Timings[%d][%d]", section, question), fmt.Sprint(milliseconds))
+
+		a, err := ParsePsychometryAnswers(form, psychometry, Shuffle{})
+		return err == nil && a.Timing(section, question) == time.Duration(milliseconds)*time.Millisecond
+	}
+
+	if err := quick.Check(timings, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: an option right past the last option of the question is invalid
+func TestParsePsychometryAnswers_pastLastOption(t *testing.T) {
+	pastLastOption := func(psychometry Psychometry) bool {
+		question := psychometry.Sections[0].Questions[0]
+
+		form := url.Values{}
+		form.Add("Sections[0][0]", fmt.Sprint(len(question.Options)))
+		_, err := ParsePsychometryAnswers(form, psychometry, Shuffle{})
+
+		form.Set("Sections[0][0]", fmt.Sprint(len(question.Options)-1))
+		answers, validErr := ParsePsychometryAnswers(form, psychometry, Shuffle{})
+
+		return err == InvalidIndex && validErr == nil && answers.Sections[0][0] == len(question.Options)-1
+	}
+
+	if err := quick.Check(pastLastOption, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+// A reading passage that several questions of a section refer to. Questions refer to its lines by number (e.g. "in
+// line 12"), so the passage is kept as lines rather than as a single text.
+type Passage struct {
+	ID    string
+	Title string
+	Lines []string
+}
+
+// A question along with its index within its section, which its form key is made of.
+type IndexedQuestion struct {
+	Index    int
+	Question Question
+}
+
+// Consecutive questions of a section that are shown together, along with the passage they refer to.
+type QuestionGroup struct {
+	// Nil for questions that do not refer to a passage.
+	Passage   *Passage
+	Questions []IndexedQuestion
+}
+
+func (s Section) Passage(id string) (Passage, bool) {
+	for _, passage := range s.Passages {
+		if passage.ID == id {
+			return passage, true
+		}
+	}
+	return Passage{}, false
+}
+
+// Groups the questions of the section by the passage they refer to, keeping their order.
+func (s Section) Groups() []QuestionGroup {
+	groups := []QuestionGroup{}
+
+	for j, question := range s.Questions {
+		last := len(groups) - 1
+		current := ""
+		if last >= 0 && groups[last].Passage != nil {
+			current = groups[last].Passage.ID
+		}
+
+		if last < 0 || current != question.PassageID {
+			group := QuestionGroup{}
+			if passage, ok := s.Passage(question.PassageID); ok {
+				group.Passage = &passage
+			}
+			groups = append(groups, group)
+			last += 1
+		}
+
+		groups[last].Questions = append(groups[last].Questions, IndexedQuestion{Index: j, Question: question})
+	}
+
+	return groups
+}
+
+// Adds the passages that the questions of a section refer to, taking them from the sections the questions were drawn
+// from. Passage IDs are unique across the bank (see `checkBankIDs`), so any source with the passage is the question's.
+func withPassages(section Section, sources []Section) Section {
+	section.Passages = []Passage{}
+
+	for _, question := range section.Questions {
+		if question.PassageID == "" {
+			continue
+		}
+		if _, ok := section.Passage(question.PassageID); ok {
+			continue
+		}
+
+		for _, source := range sources {
+			if passage, ok := source.Passage(question.PassageID); ok {
+				section.Passages = append(section.Passages, passage)
+				break
+			}
+		}
+	}
+
+	return section
+}
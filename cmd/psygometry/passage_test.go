@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+)
+
+// Test: consecutive questions that refer to the same passage are grouped together, in their original order
+func TestSectionGroups(t *testing.T) {
+	section := Section{
+		Passages: []Passage{{ID: "a"}, {ID: "b"}},
+		Questions: []Question{
+			{ID: "0"},
+			{ID: "1"},
+			{ID: "2", PassageID: "a"},
+			{ID: "3", PassageID: "a"},
+			{ID: "4", PassageID: "b"},
+			{ID: "5"},
+		},
+	}
+
+	groups := section.Groups()
+
+	expected := []struct {
+		passage string
+		indexes []int
+	}{
+		{"", []int{0, 1}},
+		{"a", []int{2, 3}},
+		{"b", []int{4}},
+		{"", []int{5}},
+	}
+	if len(groups) != len(expected) {
+		t.Fatalf("expected %d groups, got %d", len(expected), len(groups))
+	}
+	for i, group := range groups {
+		passage := ""
+		if group.Passage != nil {
+			passage = group.Passage.ID
+		}
+		if passage != expected[i].passage || len(group.Questions) != len(expected[i].indexes) {
+			t.Fatalf("group %d: expected passage %q with %v, got %q with %v", i, expected[i].passage, expected[i].indexes, passage, group.Questions)
+		}
+		for j, question := range group.Questions {
+			if question.Index != expected[i].indexes[j] || question.Question.ID != section.Questions[question.Index].ID {
+				t.Errorf("group %d: expected question %d, got %v", i, expected[i].indexes[j], question)
+			}
+		}
+	}
+}
+
+// Test: questions drawn into a new section bring the passages they refer to along
+func TestWithPassages(t *testing.T) {
+	question, kind, ok := findQuestion("fake-1-1")
+	if !ok {
+		t.Fatal("expected the question to be in the bank")
+	}
+
+	section := withPassages(Section{Kind: kind, Questions: []Question{question}}, bankSections())
+
+	if _, ok := section.Passage(question.PassageID); !ok || len(section.Passages) != 1 {
+		t.Errorf("expected only passage %q, got %v", question.PassageID, section.Passages)
+	}
+}
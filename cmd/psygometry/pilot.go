@@ -0,0 +1,130 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+)
+
+// Sections of new questions that are piloted as the experimental section of a psychometry, before they are added to
+// the bank. Like on the real test, the experimental section is not counted and students are not told which one it is.
+var pilotSections = []Section{
+	{
+		Kind: V,
+		Questions: []Question{
+			{
+				ID:            "pilot-V-0",
+				Content:       "מי ביים את הסרט 'פארגו'?",
+				Options:       []string{"האחים כהן", "האחים וורנר", "האחים מרקס", "האחים לומייר"},
+				CorrectOption: 0,
+				Topic:         Analogies,
+			},
+			{
+				ID:            "pilot-V-1",
+				Content:       "איזו מהדמויות הבאות אינה מופיעה ב'אליס בארץ הפלאות'?",
+				Options:       []string{"הכובען המטורף", "החתול משייר", "פיטר פן", "מלכת הלבבות"},
+				CorrectOption: 2,
+				Topic:         SentenceCompletion,
+			},
+		},
+	},
+	{
+		Kind: Q,
+		Questions: []Question{
+			{
+				ID:            "pilot-Q-0",
+				Content:       "אם $x^2 = 2500$ וגם $x > 0$, מהו $x$?",
+				Options:       []string{"13", "48", "50", "52"},
+				CorrectOption: 2,
+				Topic:         Algebra,
+			},
+			{
+				ID:            "pilot-Q-1",
+				Content:       "כמה צלעות יש למצולע שבאיור?",
+				Options:       []string{"5", "6", "7", "8"},
+				CorrectOption: 1,
+				Topic:         Geometry,
+				Blocks:        []Block{{Kind: ImageBlock, Asset: "hexagon.svg", Alt: "מצולע משוכלל"}},
+			},
+		},
+	},
+	{
+		Kind: E,
+		Questions: []Question{
+			{
+				ID:            "pilot-E-0",
+				Content:       "Choose the word closest in meaning to 'rapid'.",
+				Options:       []string{"slow", "quick", "heavy", "quiet"},
+				CorrectOption: 1,
+				Topic:         Restatements,
+			},
+			{
+				ID:            "pilot-E-1",
+				Content:       "She has lived here ___ 2010.",
+				Options:       []string{"for", "since", "from", "at"},
+				CorrectOption: 1,
+				Topic:         SentenceCompletion,
+			},
+		},
+	},
+}
+
+// Inserts a random pilot section at a random position among the sections of the psychometry, as an uncounted section.
+func withExperimentalSection(random *rand.Rand, psychometry Psychometry) Psychometry {
+	experimental := pilotSections[random.Intn(len(pilotSections))]
+	experimental.IsCounted = false
+	position := random.Intn(len(psychometry.Sections) + 1)
+
+	sections := make([]Section, 0, len(psychometry.Sections)+1)
+	sections = append(sections, psychometry.Sections[:position]...)
+	sections = append(sections, experimental)
+	sections = append(sections, psychometry.Sections[position:]...)
+	for i := range sections {
+		sections[i].Index = i
+	}
+
+	psychometry.Sections = sections
+	return psychometry
+}
+
+// The sections of the psychometry that are not counted, revealed to the student with the results.
+func (p *Psychometry) ExperimentalSections() []Section {
+	result := []Section{}
+	for _, section := range p.Sections {
+		if !section.IsCounted {
+			result = append(result, section)
+		}
+	}
+	return result
+}
+
+func (s Section) Number() int {
+	return s.Index + 1
+}
+
+// Responses to uncounted sections, kept apart from the rest so that piloted questions do not affect scores, review
+// schedules, or ability estimates.
+var pilotResponses = []Response{}
+
+// Path of a JSON lines file that pilot responses are persisted to, taken from the `PILOT_RESPONSES_PATH` environment
+// variable.
+//
+// When empty, pilot responses are only kept in memory.
+func pilotResponsesPath() string {
+	return os.Getenv("PILOT_RESPONSES_PATH")
+}
+
+func recordPilotResponses(added []Response) error {
+	pilotResponses = append(pilotResponses, added...)
+
+	if path := pilotResponsesPath(); path != "" {
+		return appendJSONLines(path, added)
+	}
+	return nil
+}
+
+// Statistics of the piloted questions. The discrimination of each is measured against the rest of the attempt it was
+// answered in, including its counted sections.
+func pilotItemStatistics(counted []Response, piloted []Response) []ItemStatistics {
+	all := append(append([]Response{}, counted...), piloted...)
+	return computeItemStatistics(pilotSections, all)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// Test: exactly one uncounted section is inserted, without changing the order of the counted sections
+func TestWithExperimentalSection(t *testing.T) {
+	f := func(seed int64) bool {
+		original := generateFakeData()
+		psychometry := withExperimentalSection(rand.New(rand.NewSource(seed)), original)
+
+		if len(psychometry.ExperimentalSections()) != 1 || len(psychometry.Sections) != len(original.Sections)+1 {
+			return false
+		}
+
+		counted := []Section{}
+		for i, section := range psychometry.Sections {
+			if section.Index != i {
+				return false
+			}
+			if section.IsCounted {
+				counted = append(counted, section)
+			}
+		}
+		for i, section := range counted {
+			if !reflect.DeepEqual(section.Questions, original.Sections[i].Questions) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: answers to the experimental section do not change the scores
+func TestWithExperimentalSection_notScored(t *testing.T) {
+	psychometry := withExperimentalSection(rand.New(rand.NewSource(1)), generateFakeData())
+	experimental := psychometry.ExperimentalSections()[0]
+
+	unanswered := newPsychometryAnswers(psychometry)
+	answered := newPsychometryAnswers(psychometry)
+	for j, question := range experimental.Questions {
+		answered.Sections[experimental.Index][j] = question.CorrectOption
+	}
+
+	expected, err := CalculateScoreSummary(psychometry, unanswered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := CalculateScoreSummary(psychometry, answered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", *expected, *actual)
+	}
+}
+
+// Test: responses to the experimental section are kept as pilot responses only
+func TestRecordResponses_pilot(t *testing.T) {
+	defer func(r []Response, p []Response) { responses, pilotResponses = r, p }(responses, pilotResponses)
+	responses, pilotResponses = []Response{}, []Response{}
+	t.Setenv("RESPONSES_PATH", "")
+	t.Setenv("PILOT_RESPONSES_PATH", "")
+
+	psychometry := withExperimentalSection(rand.New(rand.NewSource(1)), generateFakeData())
+	if err := recordResponses("student", "attempt", psychometry, newPsychometryAnswers(psychometry)); err != nil {
+		t.Fatal(err)
+	}
+
+	experimental := psychometry.ExperimentalSections()[0]
+	if len(pilotResponses) != len(experimental.Questions) {
+		t.Fatalf("expected %d pilot responses, got %d", len(experimental.Questions), len(pilotResponses))
+	}
+	for _, response := range responses {
+		if _, _, ok := findQuestion(response.QuestionID); !ok {
+			t.Errorf("expected only bank questions in the responses, got %s", response.QuestionID)
+		}
+	}
+
+	total := 0
+	for _, item := range pilotItemStatistics(responses, pilotResponses) {
+		total += item.Responses
+	}
+	if total != len(experimental.Questions) {
+		t.Errorf("expected statistics over %d pilot responses, got %d", len(experimental.Questions), total)
+	}
+}
@@ -6,7 +6,8 @@ import (
 	"io"
 	"log"
 	"net/http"
-	"net/url"
+	"os"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
@@ -22,21 +23,118 @@ func (t *Template) Render(w io.Writer, name string, data interface{}, c echo.Con
 	return t.templates.ExecuteTemplate(w, name, data)
 }
 
-type State struct {
-	Page        int
-	Session     string
-	Psychometry Psychometry
+type Results struct {
+	Summary    ScoreSummary
+	Weaknesses WeaknessReport
+	Timings    TimingReport
+	Changes    AnswerChangeSummary
+	// Sections that were not counted, which the student is only told about now.
+	Experimental []Section
 }
 
 var psychometries = map[string]*State{}
-var formValues = map[string]*url.Values{}
 
 func main() {
+	// Commands may be run outside of the project's directory, where there is no `.env` file
+	command := len(os.Args) > 1
 	err := godotenv.Load()
-	if err != nil {
+	if err != nil && !command {
 		log.Fatalln(err)
 	}
 
+	// Only resolved once the environment is loaded, as `.env` may set where assets are
+	assets = os.DirFS(assetsPath())
+
+	if command {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	if path := responsesPath(); path != "" {
+		responses, err = loadJSONLines[Response](path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		rebuildReviewSchedules(responses)
+	}
+
+	if path := attemptsPath(); path != "" {
+		attempts, err = loadJSONLines[Attempt](path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	if path := testDatesPath(); path != "" {
+		planned, err := loadJSONLines[PlannedTestDate](path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		rebuildTestDates(planned)
+	}
+
+	if path := wordListsPath(); path != "" {
+		imported, err := loadJSONLines[WordList](path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		applyWordLists(imported)
+	}
+
+	if path := vocabularyReviewsPath(); path != "" {
+		reviews, err := loadJSONLines[VocabularyReview](path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		rebuildVocabularySchedules(reviews)
+	}
+
+	if path := rescoresPath(); path != "" {
+		logged, err := loadJSONLines[Rescore](path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		applyRescores(attempts, logged)
+	}
+
+	if path := pilotResponsesPath(); path != "" {
+		pilotResponses, err = loadJSONLines[Response](path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	if path := examsPath(); path != "" {
+		exams, err = loadExams(path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		rebuildBank()
+		examDrafts, err = loadExamDrafts(path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	if path := answerSheetsPath(); path != "" {
+		loaded, err := loadJSONLines[AnswerSheet](path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, sheet := range loaded {
+			answerSheets[sheet.ID] = sheet
+		}
+	}
+
+	if path := answerChangesPath(); path != "" {
+		answerChanges, err = loadJSONLines[AnswerChange](path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
 	e := echo.New()
 
 	e.Use(middleware.Logger())
@@ -48,8 +146,6 @@ func main() {
 
 	e.Renderer = t
 
-	fakeData := generateFakeData()
-
 	e.GET("/", func(c echo.Context) error {
 		req := c.Request()
 		if err := req.ParseForm(); err != nil {
@@ -57,22 +153,34 @@ func main() {
 		}
 
 		session := req.Form.Get("session")
+		state, ok := psychometries[session]
+		if ok && state.Phase == Finished {
+			// A retake is a new session, so that it is shuffled differently
+			session = ""
+			ok = false
+		}
 		if session == "" {
 			session = uuid.New().String()
 		}
-		state, ok := psychometries[session]
-		if !ok || state.Page >= len(state.Psychometry.Sections) {
+		if !ok {
 			psychometry := generateFakeData()
-
-			state = &State{
-				Session:     session,
-				Page:        -1,
-				Psychometry: psychometry,
+			version := ""
+			if id := c.QueryParam("exam"); id != "" {
+				exam, found := findExam(id)
+				if !found {
+					return echo.NewHTTPError(http.StatusNotFound, "unknown exam")
+				}
+				psychometry.Sections = exam.Psychometry.Sections
+				psychometry.WritingSection = exam.WritingPrompt()
+				version = exam.VersionID()
 			}
+
+			state = newState(session, withExperimentalSection(sessionRandom(session), psychometry))
+			state.ExamVersion = version
 			psychometries[session] = state
 		}
 
-		if state.Page < 0 {
+		if state.Phase == Writing {
 			return c.Render(http.StatusOK, "writing-page", state)
 		} else {
 			return c.Render(http.StatusOK, "section-page", state)
@@ -95,33 +203,82 @@ func main() {
 			return errors.New("invalid session")
 		}
 
-		values, ok := formValues[session]
-		if !ok {
-			values = &req.Form
-			formValues[session] = values
-		} else {
-			for key, value := range req.Form {
-				values.Set(key, value[0])
-			}
+		action := Action(req.Form.Get("action"))
+		if action == "" {
+			action = Submit
+		}
+
+		state.Merge(req.Form)
+		phase, page := state.Phase, state.Page
+		if err := state.Transition(action); err != nil {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
 		}
 
-		state.Page += 1
-		if state.Page < len(state.Psychometry.Sections) {
-			return c.Render(http.StatusOK, "section", state.Psychometry.Sections[state.Page])
+		switch state.Phase {
+		case Answering:
+			return c.Render(http.StatusOK, "section-answering", state)
+		case Reviewing:
+			return c.Render(http.StatusOK, "section-review", state.Review())
 		}
 
-		answers, err := ParsePsychometryAnswers(*values, state.Psychometry)
+		// The attempt is only finished once it is scored and recorded, so that it can be submitted again if either fails
+		recorded := false
+		defer func() {
+			if !recorded {
+				state.Phase, state.Page = phase, page
+			}
+		}()
+
+		answers, err := ParsePsychometryAnswers(state.Values, state.Psychometry, state.Shuffle)
 		if err != nil {
 			return err
 		}
-
-		summary, err := CalculateScoreSummary(fakeData, *answers)
+		summary, err := CalculateScoreSummary(state.Psychometry, *answers)
 		if err != nil {
 			return err
 		}
 
-		return c.Render(http.StatusCreated, "scores", summary)
+		if err := recordResponses(studentID(c), session, state.Psychometry, *answers); err != nil {
+			return err
+		}
+
+		weaknesses := diagnoseWeaknesses(state.Psychometry, *answers, session, studentResponses(studentID(c), ""))
+		timings := reportTimings(state.Psychometry, *answers, session, responses)
+		changes := summarizeAnswerChanges(state.Psychometry, session, answerChanges)
+
+		attempt := Attempt{
+			Student:     studentID(c),
+			Session:     session,
+			ExamVersion: state.ExamVersion,
+			Started:     state.Started,
+			Finished:    time.Now(),
+			Summary:     *summary,
+		}
+		if err := recordAttempt(attempt); err != nil {
+			return err
+		}
+		recorded = true
+
+		displayed := state.Displayed()
+		return c.Render(http.StatusCreated, "results", Results{
+			Summary:      *summary,
+			Weaknesses:   weaknesses,
+			Timings:      timings,
+			Changes:      changes,
+			Experimental: displayed.ExperimentalSections(),
+		})
 	})
 
+	registerAssets(e)
+	registerAnswerChanges(e)
+	registerEssayPractice(e)
+	registerEssayScans(e)
+	registerDrills(e)
+	registerAdaptive(e)
+	registerReview(e)
+	registerVocabulary(e)
+	registerDashboard(e)
+	registerAdmin(e)
+
 	e.Logger.Fatal(e.Start(":1714"))
 }
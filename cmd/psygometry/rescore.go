@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// A change to the score of an attempt made by rescoring it, kept in an audit log.
+type Rescore struct {
+	Session     string
+	Student     string
+	ExamVersion string
+	// Version whose answer key was used, which is the version taken unless corrected keys were applied.
+	KeyVersion string
+	Before     ScoreSummary
+	After      ScoreSummary
+	Time       time.Time
+}
+
+// Path of the JSON lines audit log of rescores, taken from the `RESCORES_PATH` environment variable. The `rescore`
+// command appends to it, and the server applies it to the attempts it loads.
+func rescoresPath() string {
+	return os.Getenv("RESCORES_PATH")
+}
+
+// Replaces the score of every rescored attempt with its latest score, as attempts themselves are never rewritten.
+func applyRescores(attempts []Attempt, rescores []Rescore) {
+	latest := map[string]ScoreSummary{}
+	for _, rescore := range rescores {
+		latest[rescore.Session] = rescore.After
+	}
+
+	for i, attempt := range attempts {
+		if summary, ok := latest[attempt.Session]; ok {
+			attempts[i].Summary = summary
+		}
+	}
+}
+
+// Replaces the answer key of every question with the key of the question with the same ID in `key`, leaving the
+// questions as they were shown.
+func withAnswerKey(psychometry Psychometry, key Psychometry) Psychometry {
+	corrected := map[string]Question{}
+	for _, section := range key.Sections {
+		for _, question := range section.Questions {
+			corrected[question.ID] = question
+		}
+	}
+
+	sections := make([]Section, len(psychometry.Sections))
+	for i, section := range psychometry.Sections {
+		sections[i] = section
+		sections[i].Questions = make([]Question, len(section.Questions))
+		for j, question := range section.Questions {
+			if fixed, ok := corrected[question.ID]; ok {
+				question.Type = fixed.Type
+				question.CorrectOption = fixed.CorrectOption
+				question.CorrectOptions = fixed.CorrectOptions
+				question.NumericAnswer = fixed.NumericAnswer
+				question.Tolerance = fixed.Tolerance
+			}
+			sections[i].Questions[j] = question
+		}
+	}
+
+	psychometry.Sections = sections
+	return psychometry
+}
+
+// Rebuilds the answers of an attempt from the responses recorded for it.
+func attemptAnswers(psychometry Psychometry, session string, responses []Response) PsychometryAnswers {
+	answers := newPsychometryAnswers(psychometry)
+
+	given := map[string]Response{}
+	for _, response := range responses {
+		if response.Attempt == session {
+			given[response.QuestionID] = response
+		}
+	}
+
+	for i, section := range psychometry.Sections {
+		for j, question := range section.Questions {
+			if response, ok := given[question.ID]; ok {
+				answers.Sections[i][j] = response.Option
+				answers.Entries[i][j] = response.Entry
+			}
+		}
+	}
+
+	return answers
+}
+
+// Rescores every attempt of an exam against the version it was taken in, or against the answer key of the latest
+// version when `corrected` is set, and returns the attempts whose scores changed.
+//
+// The writing section is not scored again, since its score does not depend on any answer key.
+func rescoreAttempts(examID string, corrected bool, attempts []Attempt, responses []Response, now time.Time) ([]Rescore, error) {
+	latest, ok := findExam(examID)
+	if !ok {
+		return nil, fmt.Errorf("unknown exam %q", examID)
+	}
+
+	changes := []Rescore{}
+	for _, attempt := range attempts {
+		taken, ok := findExamVersion(attempt.ExamVersion)
+		if !ok || taken.ID != examID {
+			continue
+		}
+
+		psychometry := taken.Psychometry
+		key := taken
+		if corrected {
+			psychometry = withAnswerKey(psychometry, latest.Psychometry)
+			key = latest
+		}
+
+		answers := attemptAnswers(psychometry, attempt.Session, responses)
+		summary := combineScores(calculateStaticScores(psychometry, answers), attempt.Summary.WritingScore)
+		if summary.StaticScores == attempt.Summary.StaticScores && summary.DynamicScores == attempt.Summary.DynamicScores {
+			continue
+		}
+
+		changes = append(changes, Rescore{
+			Session:     attempt.Session,
+			Student:     attempt.Student,
+			ExamVersion: attempt.ExamVersion,
+			KeyVersion:  key.VersionID(),
+			Before:      attempt.Summary,
+			After:       *summary,
+			Time:        now,
+		})
+	}
+
+	return changes, nil
+}
+
+func rescoreCommand(args []string) error {
+	flags := flag.NewFlagSet("rescore", flag.ContinueOnError)
+	examID := flags.String("exam", "", "ID of the exam whose attempts are rescored")
+	corrected := flags.Bool("corrected", false, "apply the answer key of the latest version of the exam, instead of the key of the version each attempt took")
+	dryRun := flags.Bool("dry-run", false, "only report the changed scores, without recording them")
+	examsInput := flags.String("exams", examsPath(), "path of the directory of exam files")
+	attemptsInput := flags.String("attempts", attemptsPath(), "path of the persisted attempts")
+	responsesInput := flags.String("responses", responsesPath(), "path of the persisted responses")
+	output := flags.String("log", rescoresPath(), "path of the audit log that changed scores are recorded in")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *examID == "" {
+		return errors.New("missing exam ID (pass -exam)")
+	}
+	for name, path := range map[string]string{"exams": *examsInput, "attempts": *attemptsInput, "responses": *responsesInput} {
+		if path == "" {
+			return fmt.Errorf("missing %s path (pass -%s)", name, name)
+		}
+	}
+	if *output == "" && !*dryRun {
+		return errors.New("missing audit log path (set RESCORES_PATH, pass -log, or pass -dry-run)")
+	}
+
+	var err error
+	exams, err = loadExams(*examsInput)
+	if err != nil {
+		return err
+	}
+	loadedAttempts, err := loadJSONLines[Attempt](*attemptsInput)
+	if err != nil {
+		return err
+	}
+	loadedResponses, err := loadJSONLines[Response](*responsesInput)
+	if err != nil {
+		return err
+	}
+
+	// Attempts rescored before are compared with their latest score
+	if *output != "" {
+		logged, err := loadJSONLines[Rescore](*output)
+		if err != nil {
+			return err
+		}
+		applyRescores(loadedAttempts, logged)
+	}
+
+	changes, err := rescoreAttempts(*examID, *corrected, loadedAttempts, loadedResponses, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		fmt.Printf("%s\t%s\t%d -> %d\n", change.Session, change.Student,
+			change.Before.DynamicScores.MultiCategoryUniform, change.After.DynamicScores.MultiCategoryUniform)
+	}
+	fmt.Printf("%d attempts changed\n", len(changes))
+
+	if *dryRun || len(changes) == 0 {
+		return nil
+	}
+	return appendJSONLines(*output, changes)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Test: attempts keep the score of the version they took, unless the corrected key is applied, and every change is logged
+func TestRescoreAttempts(t *testing.T) {
+	defer func(original []Exam) {
+		exams = original
+	}(exams)
+
+	taken := generateFakeData()
+	fixed := withAnswerKey(taken, Psychometry{})
+	fixed.Sections[0].Questions = append([]Question{}, fixed.Sections[0].Questions...)
+	fixed.Sections[0].Questions[0].CorrectOption = (taken.Sections[0].Questions[0].CorrectOption + 1) % 4
+	exams = []Exam{{ID: "fake", Version: 1, Psychometry: taken}, {ID: "fake", Version: 2, Psychometry: fixed}}
+
+	// The student chose the option that the fixed version makes correct
+	answers := newPsychometryAnswers(taken)
+	answers.Sections[0][0] = fixed.Sections[0].Questions[0].CorrectOption
+	summary, err := CalculateScoreSummary(taken, answers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := []Attempt{
+		{Session: "first", ExamVersion: "fake.v1", Summary: *summary},
+		{Session: "other", ExamVersion: "other.v1", Summary: *summary},
+	}
+	responses := []Response{{Attempt: "first", QuestionID: taken.Sections[0].Questions[0].ID, Option: answers.Sections[0][0]}}
+
+	changes, err := rescoreAttempts("fake", false, attempts, responses, time.Now())
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("expected the version taken to score the same, got %v (%v)", changes, err)
+	}
+
+	changes, err = rescoreAttempts("fake", true, attempts, responses, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Session != "first" || changes[0].KeyVersion != "fake.v2" {
+		t.Fatalf("expected only the first attempt to be rescored with the fixed key, got %v", changes)
+	}
+	if changes[0].After.StaticScores.VRaw != changes[0].Before.StaticScores.VRaw+1 {
+		t.Errorf("expected one more correct verbal answer, got %v", changes[0])
+	}
+
+	applyRescores(attempts, changes)
+	if !reflect.DeepEqual(attempts[0].Summary, changes[0].After) || !reflect.DeepEqual(attempts[1].Summary, *summary) {
+		t.Errorf("expected only the rescored attempt to change, got %v", attempts)
+	}
+	if changes, _ := rescoreAttempts("fake", true, attempts, responses, time.Now()); len(changes) != 0 {
+		t.Errorf("expected rescoring again to change nothing, got %v", changes)
+	}
+}
+
+// Test: exam files are named after their versions, and only the latest version of each exam is in the bank
+func TestLatestExams(t *testing.T) {
+	defer func(original []Exam) {
+		exams = original
+	}(exams)
+
+	for name, expected := range map[string]string{"a.json": "a.v1", "a.v12.json": "a.v12", "a.b.json": "", "a.v0.json": ""} {
+		id, version, err := parseExamFileName(name)
+		if actual := (Exam{ID: id, Version: version}).VersionID(); (err == nil) != (expected != "") || err == nil && actual != expected {
+			t.Errorf("%s: expected %q, got %q (%v)", name, expected, actual, err)
+		}
+	}
+
+	exams = []Exam{{ID: "a", Version: 1}, {ID: "a", Version: 2}, {ID: "b", Version: 1}}
+	latest := latestExams()
+	if len(latest) != 2 || latest[0].VersionID() != "a.v2" || latest[1].VersionID() != "b.v1" {
+		t.Errorf("expected the latest version of every exam, got %v", latest)
+	}
+}
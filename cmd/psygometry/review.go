@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// The most questions reviewed in a single deck.
+const reviewDeckSize = 20
+
+// A deck of questions due for review, grouped into a section per kind.
+type ReviewDeck struct {
+	Session     string
+	Psychometry Psychometry
+	Retention   Retention
+}
+
+type ReviewResult struct {
+	Feedback  []QuestionFeedback
+	Retention Retention
+}
+
+var reviewDecks = map[string]*ReviewDeck{}
+
+func buildReviewPsychometry(ids []string) Psychometry {
+	byKind := map[SectionKind][]Question{}
+	for _, id := range ids {
+		question, kind, ok := findQuestion(id)
+		if ok {
+			byKind[kind] = append(byKind[kind], question)
+		}
+	}
+
+	psychometry := Psychometry{Sections: []Section{}}
+	for _, kind := range []SectionKind{V, Q, E} {
+		if len(byKind[kind]) == 0 {
+			continue
+		}
+
+		section := Section{
+			Kind:      kind,
+			Index:     len(psychometry.Sections),
+			IsCounted: true,
+			Questions: byKind[kind],
+		}
+		psychometry.Sections = append(psychometry.Sections, withPassages(section, bankSections()))
+	}
+
+	return psychometry
+}
+
+func registerReview(e *echo.Echo) {
+	e.GET("/review", func(c echo.Context) error {
+		student := studentID(c)
+		now := time.Now()
+
+		due := dueQuestions(student, now)
+		deck := &ReviewDeck{
+			Session:     uuid.New().String(),
+			Psychometry: buildReviewPsychometry(due[:min(len(due), reviewDeckSize)]),
+			Retention:   calculateRetention(reviewSchedules[student], now),
+		}
+		reviewDecks[deck.Session] = deck
+
+		return c.Render(http.StatusOK, "review-page", deck)
+	})
+
+	e.POST("/review/answers", func(c echo.Context) error {
+		req := c.Request()
+		if err := req.ParseForm(); err != nil {
+			return err
+		}
+
+		deck, ok := reviewDecks[req.Form.Get("session")]
+		if !ok {
+			// TODO: handle this
+			return errors.New("invalid session")
+		}
+
+		answers, err := ParsePsychometryAnswers(req.Form, deck.Psychometry, Shuffle{})
+		if err != nil {
+			return err
+		}
+
+		delete(reviewDecks, deck.Session)
+
+		student := studentID(c)
+		if err := recordResponses(student, deck.Session, deck.Psychometry, *answers); err != nil {
+			return err
+		}
+
+		result := ReviewResult{
+			Feedback:  []QuestionFeedback{},
+			Retention: calculateRetention(reviewSchedules[student], time.Now()),
+		}
+		for i, section := range deck.Psychometry.Sections {
+			result.Feedback = append(result.Feedback, questionFeedback(section, answers.Sections[i], answers.Entries[i])...)
+		}
+
+		return c.Render(http.StatusCreated, "review-results", result)
+	})
+}
@@ -4,6 +4,8 @@ type WritingScore struct {
 	Linguistic  int
 	Content     int
 	Explanation string
+	// Reasons to have the grade checked by a person, when the grader could not fully stand behind it.
+	Warnings []string
 }
 
 type Scores struct {
@@ -90,6 +92,11 @@ func CalculateScoreSummary(psychometry Psychometry, answers PsychometryAnswers)
 		return nil, err
 	}
 
+	return combineScores(static, *writing), nil
+}
+
+// Combines the scores of the multiple-choice sections with the score of the writing section.
+func combineScores(static Scores, writing WritingScore) *ScoreSummary {
 	dynamic := Scores{}
 
 	dynamic.VRaw = writing.Content + writing.Linguistic + static.VRaw
@@ -112,10 +119,10 @@ func CalculateScoreSummary(psychometry Psychometry, answers PsychometryAnswers)
 	dynamic.QuantitativeFocusGeneral = generalMeasurementRange(dynamic.QuantitativeFocusUniform)
 
 	summary := &ScoreSummary{
-		WritingScore:  *writing,
+		WritingScore:  writing,
 		StaticScores:  static,
 		DynamicScores: dynamic,
 	}
 
-	return summary, nil
+	return summary
 }
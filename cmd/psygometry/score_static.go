@@ -5,13 +5,13 @@ package main
 // "Raw scores on the multiple-choice sections: Each correct answer is worth one point. The number of correct answers in each domain is equal to the raw score in that domain." - [nite.org.il]
 //
 // [nite.org.il]: https://www.nite.org.il/psychometric-entrance-test/scores/calculation/?lang=en
-func rawCategoryScore(psychometrySections []Section, answerSections [][]int) int {
+func rawCategoryScore(psychometrySections []Section, answerSections [][]int, entrySections [][]string) int {
 	score := 0
 
 	for i, section := range psychometrySections {
 		for j, question := range section.Questions {
 			option := answerSections[i][j]
-			if option == question.CorrectOption {
+			if question.IsCorrect(option, entrySections[i][j]) {
 				score += 1
 			}
 		}
@@ -44,9 +44,9 @@ func uniformCategoryScore(psychometrySections []Section, rawScore int) int {
 func calculateStaticScores(psychometry Psychometry, answers PsychometryAnswers) Scores {
 	scores := Scores{}
 
-	scores.VRaw = rawCategoryScore(psychometry.GetSections(V), answers.GetSections(psychometry, V))
-	scores.QRaw = rawCategoryScore(psychometry.GetSections(Q), answers.GetSections(psychometry, Q))
-	scores.ERaw = rawCategoryScore(psychometry.GetSections(E), answers.GetSections(psychometry, E))
+	scores.VRaw = rawCategoryScore(psychometry.GetSections(V), answers.GetSections(psychometry, V), answers.GetEntries(psychometry, V))
+	scores.QRaw = rawCategoryScore(psychometry.GetSections(Q), answers.GetSections(psychometry, Q), answers.GetEntries(psychometry, Q))
+	scores.ERaw = rawCategoryScore(psychometry.GetSections(E), answers.GetSections(psychometry, E), answers.GetEntries(psychometry, E))
 
 	scores.VUniform = uniformCategoryScore(psychometry.GetSections(V), scores.VRaw)
 	scores.QUniform = uniformCategoryScore(psychometry.GetSections(Q), scores.QRaw)
@@ -34,7 +34,7 @@ func makeAnswerSectionArray(rand *rand.Rand, size int) [][]int {
 
 func (scoreSummaryInput) Generate(rand *rand.Rand, size int) reflect.Value {
 	psychometry := Psychometry{
-		WritingSection: "",
+		WritingSection: WritingPrompt{},
 		Sections:       make([]Section, size),
 	}
 	answers := PsychometryAnswers{
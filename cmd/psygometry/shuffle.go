@@ -0,0 +1,136 @@
+package main
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"slices"
+)
+
+// How the sections and options of a psychometry are shuffled for a single session. Form values use the indexes as
+// displayed, and are translated back to the canonical indexes of the psychometry before scoring.
+//
+// The zero value shows everything in its canonical order.
+type Shuffle struct {
+	// Canonical index of the section displayed at every position.
+	Sections []int
+	// Canonical index of every displayed option, by the canonical indexes of its section and question.
+	Options [][][]int
+}
+
+// How many shuffles of the sections are tried before settling on one that breaks the rules.
+const sectionShuffleAttempts = 100
+
+// A random number generator that is the same for every request of a session, so that reloading the page shows the
+// same psychometry.
+func sessionRandom(session string) *rand.Rand {
+	hash := fnv.New64a()
+	hash.Write([]byte(session))
+	return rand.New(rand.NewSource(int64(hash.Sum64())))
+}
+
+func shufflePsychometry(random *rand.Rand, psychometry Psychometry) Shuffle {
+	shuffle := Shuffle{Sections: shuffleSections(random, psychometry.Sections)}
+
+	for _, section := range psychometry.Sections {
+		options := [][]int{}
+		for _, question := range section.Questions {
+			options = append(options, random.Perm(len(question.Options)))
+		}
+		shuffle.Options = append(shuffle.Options, options)
+	}
+
+	return shuffle
+}
+
+// Shuffles the order of the sections, keeping to the rule of the real test that two sections of the same kind are never
+// one after the other.
+func shuffleSections(random *rand.Rand, sections []Section) []int {
+	var order []int
+	for attempt := 0; attempt < sectionShuffleAttempts; attempt++ {
+		order = random.Perm(len(sections))
+		if alternatesKinds(sections, order) {
+			break
+		}
+	}
+	return order
+}
+
+func alternatesKinds(sections []Section, order []int) bool {
+	for i := 1; i < len(order); i++ {
+		if sections[order[i]].Kind == sections[order[i-1]].Kind {
+			return false
+		}
+	}
+	return true
+}
+
+// Canonical index of the section displayed at a position.
+func (s Shuffle) section(displayed int) int {
+	if s.Sections == nil {
+		return displayed
+	}
+	return s.Sections[displayed]
+}
+
+// Canonical index of a displayed option, where out of range options (such as -1 for no option) are kept as they are.
+func (s Shuffle) option(section int, question int, displayed int) int {
+	if s.Options == nil || displayed < 0 || displayed >= len(s.Options[section][question]) {
+		return displayed
+	}
+	return s.Options[section][question][displayed]
+}
+
+// The psychometry as the student sees it, with every section indexed by its displayed position.
+func (s Shuffle) Display(psychometry Psychometry) Psychometry {
+	displayed := psychometry
+	displayed.Sections = []Section{}
+
+	for i := range psychometry.Sections {
+		canonical := s.section(i)
+		section := psychometry.Sections[canonical]
+		section.Index = i
+
+		questions := []Question{}
+		for j := range section.Questions {
+			questions = append(questions, s.question(psychometry, i, j))
+		}
+		section.Questions = questions
+
+		displayed.Sections = append(displayed.Sections, section)
+	}
+
+	return displayed
+}
+
+// A single question as the student sees it, by the displayed index of its section, without displaying the rest of the
+// psychometry.
+func (s Shuffle) question(psychometry Psychometry, section int, index int) Question {
+	canonical := s.section(section)
+	question := psychometry.Sections[canonical].Questions[index]
+
+	shuffled := question
+	shuffled.Options = make([]string, len(question.Options))
+	if question.OptionBlocks != nil {
+		shuffled.OptionBlocks = make([][]Block, len(question.Options))
+	}
+	for k := range question.Options {
+		original := s.option(canonical, index, k)
+		shuffled.Options[k] = question.Options[original]
+		if original < len(question.OptionBlocks) {
+			shuffled.OptionBlocks[k] = question.OptionBlocks[original]
+		}
+		if original == question.CorrectOption {
+			shuffled.CorrectOption = k
+		}
+	}
+	if question.CorrectOptions != nil {
+		shuffled.CorrectOptions = []int{}
+		for k := range question.Options {
+			if slices.Contains(question.CorrectOptions, s.option(canonical, index, k)) {
+				shuffled.CorrectOptions = append(shuffled.CorrectOptions, k)
+			}
+		}
+	}
+
+	return shuffled
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// The most sections (and questions per section) of the psychometries shuffled by the checks below, which would be slow
+// to run many times over the full size of generated psychometries.
+const shuffledSize = 12
+
+// Test: a session is always shuffled the same way
+func TestShufflePsychometry_deterministic(t *testing.T) {
+	f := func(session string, seed int64, size uint8) bool {
+		psychometry := Psychometry{Sections: makeSectionArray(rand.New(rand.NewSource(seed)), int(size%shuffledSize))}
+		first := shufflePsychometry(sessionRandom(session), psychometry)
+		second := shufflePsychometry(sessionRandom(session), psychometry)
+		return reflect.DeepEqual(first, second)
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: displayed answers are parsed into the canonical options they were shown as
+func TestParsePsychometryAnswers_shuffled(t *testing.T) {
+	f := func(seed int64, size uint8) bool {
+		random := rand.New(rand.NewSource(seed))
+		psychometry := Psychometry{Sections: makeSectionArray(random, int(size%shuffledSize))}
+		for i := range psychometry.Sections {
+			for j := range psychometry.Sections[i].Questions {
+				for k := range psychometry.Sections[i].Questions[j].Options {
+					psychometry.Sections[i].Questions[j].Options[k] = fmt.Sprint(i, j, k)
+				}
+			}
+		}
+
+		shuffle := shufflePsychometry(random, psychometry)
+		displayed := shuffle.Display(psychometry)
+
+		form := url.Values{}
+		chosen := map[string]int{}
+		for i, section := range displayed.Sections {
+			for j := range section.Questions {
+				key := fmt.Sprintf("Sections[%d][%d]", i, j)
+				chosen[key] = random.Intn(len(section.Questions[j].Options))
+				form.Set(key, fmt.Sprint(chosen[key]))
+			}
+		}
+
+		answers, err := ParsePsychometryAnswers(form, psychometry, shuffle)
+		if err != nil {
+			return false
+		}
+
+		for i, section := range displayed.Sections {
+			canonical := shuffle.section(i)
+			for j, question := range section.Questions {
+				option := chosen[fmt.Sprintf("Sections[%d][%d]", i, j)]
+				answer := answers.Sections[canonical][j]
+
+				if question.Options[option] != psychometry.Sections[canonical].Questions[j].Options[answer] {
+					return false
+				}
+				if (option == question.CorrectOption) != (answer == psychometry.Sections[canonical].Questions[j].CorrectOption) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: sections of the same kind are never one after the other, when the sections allow it
+func TestShuffleSections_alternatesKinds(t *testing.T) {
+	f := func(session string) bool {
+		psychometry := withExperimentalSection(sessionRandom(session), generateFakeData())
+		order := shuffleSections(sessionRandom(session), psychometry.Sections)
+		return alternatesKinds(psychometry.Sections, order)
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// A card in a spaced-repetition schedule, scheduled with the SM-2 algorithm.
+type ReviewCard struct {
+	// Days until the card is due again, after its last review.
+	Interval    int
+	Repetitions int
+	Ease        float64
+	Due         time.Time
+
+	Reviews int
+	// Reviews in which the card was forgotten.
+	Lapses int
+}
+
+func newReviewCard(now time.Time) *ReviewCard {
+	return &ReviewCard{
+		Interval: 1,
+		Ease:     2.5,
+		Due:      now.Add(day),
+	}
+}
+
+func (c *ReviewCard) IsDue(now time.Time) bool {
+	return !now.Before(c.Due)
+}
+
+// Reschedules the card after a review, where `quality` grades the recall from 0 (forgotten) to 5 (perfect).
+func (c *ReviewCard) Review(quality int, now time.Time) {
+	c.Reviews += 1
+
+	if quality >= 3 {
+		switch c.Repetitions {
+		case 0:
+			c.Interval = 1
+		case 1:
+			c.Interval = 6
+		default:
+			c.Interval = int(math.Round(float64(c.Interval) * c.Ease))
+		}
+		c.Repetitions += 1
+	} else {
+		c.Lapses += 1
+		c.Repetitions = 0
+		c.Interval = 1
+	}
+
+	penalty := float64(5 - quality)
+	c.Ease = math.Max(1.3, c.Ease+0.1-penalty*(0.08+penalty*0.02))
+	c.Due = now.Add(time.Duration(c.Interval) * day)
+}
+
+// The grade given to a review, based on whether the question was answered correctly.
+func reviewQuality(correct bool) int {
+	if correct {
+		return 4
+	}
+	return 1
+}
+
+// Every student's schedule of missed questions, by question ID.
+var reviewSchedules = map[string]map[string]*ReviewCard{}
+
+// Updates the student's schedule with a response from any practice mode.
+//
+// A question answered incorrectly enters the schedule, and answering a question that is due counts as its review.
+func updateReviewSchedule(response Response) {
+	schedule, ok := reviewSchedules[response.Student]
+	if !ok {
+		schedule = map[string]*ReviewCard{}
+		reviewSchedules[response.Student] = schedule
+	}
+
+	card, ok := schedule[response.QuestionID]
+	if !ok {
+		if !response.Correct {
+			schedule[response.QuestionID] = newReviewCard(response.Time)
+		}
+		return
+	}
+
+	if card.IsDue(response.Time) {
+		card.Review(reviewQuality(response.Correct), response.Time)
+	}
+}
+
+// Rebuilds every schedule from responses recorded before the server started.
+func rebuildReviewSchedules(responses []Response) {
+	sorted := make([]Response, len(responses))
+	copy(sorted, responses)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	reviewSchedules = map[string]map[string]*ReviewCard{}
+	for _, response := range sorted {
+		updateReviewSchedule(response)
+	}
+}
+
+// The IDs of the student's questions that are due for review, those that have been due the longest first.
+func dueQuestions(student string, now time.Time) []string {
+	due := []string{}
+	schedule := reviewSchedules[student]
+	for id, card := range schedule {
+		if card.IsDue(now) {
+			due = append(due, id)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return schedule[due[i]].Due.Before(schedule[due[j]].Due)
+	})
+	return due
+}
+
+type Retention struct {
+	Cards   int
+	Reviews int
+	// The proportion of reviews in which the question was remembered.
+	Rate float64
+	// Cards that are due now, and cards that will be due by the same time tomorrow.
+	DueToday    int
+	DueTomorrow int
+}
+
+func (r Retention) Percent() int {
+	return int(math.Round(r.Rate * 100))
+}
+
+func calculateRetention(schedule map[string]*ReviewCard, now time.Time) Retention {
+	retention := Retention{Cards: len(schedule)}
+
+	lapses := 0
+	for _, card := range schedule {
+		retention.Reviews += card.Reviews
+		lapses += card.Lapses
+
+		if card.IsDue(now) {
+			retention.DueToday += 1
+		} else if card.IsDue(now.Add(day)) {
+			retention.DueTomorrow += 1
+		}
+	}
+
+	if retention.Reviews > 0 {
+		retention.Rate = float64(retention.Reviews-lapses) / float64(retention.Reviews)
+	}
+
+	return retention
+}
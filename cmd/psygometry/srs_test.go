@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// Test: the ease never falls below 1.3, and a forgotten card is always due the next day
+func TestReviewCard_bounds(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	bounds := func(qualities []uint8) bool {
+		card := newReviewCard(now)
+		for _, quality := range qualities {
+			card.Review(int(quality%6), now)
+			if card.Ease < 1.3 {
+				return false
+			}
+			if quality%6 < 3 && card.Due != now.Add(day) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(bounds, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: consecutive successful reviews resurface a card at increasing intervals
+func TestReviewCard_increasingIntervals(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	card := newReviewCard(now)
+
+	previous := 0
+	for range 6 {
+		now = card.Due
+		card.Review(reviewQuality(true), now)
+		if card.Interval < previous {
+			t.Errorf("interval decreased from %d to %d", previous, card.Interval)
+		}
+		previous = card.Interval
+	}
+
+	if previous <= 6 {
+		t.Errorf("expected the interval to grow beyond 6 days, got %d", previous)
+	}
+}
+
+// Test: only missed questions enter the schedule, and answering a due question reviews it
+func TestUpdateReviewSchedule(t *testing.T) {
+	t.Cleanup(func() { reviewSchedules = map[string]map[string]*ReviewCard{} })
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	recorded := []Response{
+		{Student: "s", QuestionID: "right", Correct: true, Time: now},
+		{Student: "s", QuestionID: "wrong", Correct: false, Time: now},
+		{Student: "s", QuestionID: "wrong", Correct: true, Time: now.Add(time.Hour)},
+		{Student: "s", QuestionID: "wrong", Correct: true, Time: now.Add(day)},
+	}
+	rebuildReviewSchedules(recorded)
+
+	schedule := reviewSchedules["s"]
+	if _, ok := schedule["right"]; ok {
+		t.Error("a question answered correctly entered the schedule")
+	}
+
+	card, ok := schedule["wrong"]
+	if !ok {
+		t.Fatal("a missed question did not enter the schedule")
+	}
+	if card.Reviews != 1 {
+		t.Errorf("expected only the due answer to count as a review, got %d reviews", card.Reviews)
+	}
+
+	retention := calculateRetention(schedule, now.Add(day))
+	if retention.Cards != 1 || retention.Rate != 1 || retention.DueToday != 0 {
+		t.Errorf("unexpected retention %v", retention)
+	}
+}
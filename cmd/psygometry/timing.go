@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"time"
+)
+
+// Questions that took longer than this multiple of their average time are highlighted.
+const slowQuestionFactor = 2
+
+type QuestionTiming struct {
+	Number  int
+	Content template.HTML
+	Time    time.Duration
+	// The average time spent on the question in other attempts, or in this section when there are none.
+	Average time.Duration
+	Slow    bool
+}
+
+type SectionTiming struct {
+	Kind      SectionKind
+	Total     time.Duration
+	Questions []QuestionTiming
+}
+
+type TimingReport struct {
+	Sections []SectionTiming
+}
+
+func formatSeconds(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+func (t QuestionTiming) FormattedTime() string {
+	return formatSeconds(t.Time)
+}
+
+func (t QuestionTiming) FormattedAverage() string {
+	return formatSeconds(t.Average)
+}
+
+func (t SectionTiming) FormattedTotal() string {
+	return formatSeconds(t.Total)
+}
+
+// Reports the time spent on every question of the attempt, compared to the time spent on it in other attempts.
+func reportTimings(psychometry Psychometry, answers PsychometryAnswers, attempt string, history []Response) TimingReport {
+	totals := map[string]time.Duration{}
+	counts := map[string]int{}
+	for _, response := range history {
+		if response.Attempt != attempt && response.Duration > 0 {
+			totals[response.QuestionID] += response.Duration
+			counts[response.QuestionID] += 1
+		}
+	}
+
+	report := TimingReport{Sections: []SectionTiming{}}
+	for i, section := range psychometry.Sections {
+		sectionTiming := SectionTiming{Kind: section.Kind, Questions: []QuestionTiming{}}
+
+		timed := 0
+		for j := range section.Questions {
+			if answers.Timing(i, j) > 0 {
+				sectionTiming.Total += answers.Timing(i, j)
+				timed += 1
+			}
+		}
+		if timed == 0 {
+			continue
+		}
+		sectionAverage := sectionTiming.Total / time.Duration(timed)
+
+		for j, question := range section.Questions {
+			timing := QuestionTiming{
+				Number:  j + 1,
+				Content: question.RenderedContent(),
+				Time:    answers.Timing(i, j),
+				Average: sectionAverage,
+			}
+			if counts[question.ID] > 0 {
+				timing.Average = totals[question.ID] / time.Duration(counts[question.ID])
+			}
+			timing.Slow = timing.Time > slowQuestionFactor*timing.Average
+
+			sectionTiming.Questions = append(sectionTiming.Questions, timing)
+		}
+
+		report.Sections = append(report.Sections, sectionTiming)
+	}
+
+	return report
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Test: questions are highlighted when they took far longer than they took in other attempts
+func TestReportTimings_slow(t *testing.T) {
+	psychometry := generateFakeData()
+	answers := newPsychometryAnswers(psychometry)
+	answers.Timings[0][0] = 3 * time.Minute
+	answers.Timings[0][1] = time.Minute
+
+	history := []Response{
+		{Attempt: "other", QuestionID: "fake-0-0", Duration: time.Minute},
+		{Attempt: "other", QuestionID: "fake-0-1", Duration: time.Minute},
+		{Attempt: "current", QuestionID: "fake-0-1", Duration: time.Hour},
+	}
+
+	report := reportTimings(psychometry, answers, "current", history)
+
+	if len(report.Sections) != 1 {
+		t.Fatalf("expected only the timed section to be reported, got %d sections", len(report.Sections))
+	}
+	section := report.Sections[0]
+	if section.Total != 4*time.Minute {
+		t.Errorf("expected a total of 4 minutes, got %v", section.Total)
+	}
+	if !section.Questions[0].Slow || section.Questions[1].Slow {
+		t.Errorf("expected only the first question to be slow, got %v", section.Questions)
+	}
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// The most words quizzed at once.
+const vocabularyQuizSize = 10
+
+// How many options every vocabulary question has, like the questions of the real test.
+const vocabularyOptions = 4
+
+type WordListKind string
+
+const (
+	// Hebrew words with their definitions in Hebrew, for the verbal domain.
+	HebrewDefinitions WordListKind = "he-he"
+	// English words with their translations to Hebrew, for the English domain.
+	EnglishTranslations WordListKind = "en-he"
+)
+
+var wordListSections = map[WordListKind]SectionKind{
+	HebrewDefinitions:   V,
+	EnglishTranslations: E,
+}
+
+type Word struct {
+	Term    string
+	Meaning string
+}
+
+type WordList struct {
+	ID    string
+	Name  string
+	Kind  WordListKind
+	Words []Word
+}
+
+func (l *WordList) wordID(word Word) string {
+	return l.ID + "/" + word.Term
+}
+
+var wordLists = map[string]*WordList{
+	"he-basics": {
+		ID:   "he-basics",
+		Name: "מילים נפוצות בפרק המילולי",
+		Kind: HebrewDefinitions,
+		Words: []Word{
+			{Term: "אמביוולנטי", Meaning: "בעל רגשות סותרים כלפי אותו דבר"},
+			{Term: "אפמרי", Meaning: "חולף, קצר מועד"},
+			{Term: "דוגמטי", Meaning: "נאחז בדעותיו ללא ביקורת"},
+			{Term: "הגמוניה", Meaning: "שליטה או הובלה של גורם אחד על אחרים"},
+			{Term: "לקוני", Meaning: "קצר ותמציתי"},
+			{Term: "פרגמטי", Meaning: "מעשי, מכוון לתוצאות"},
+			{Term: "רטרואקטיבי", Meaning: "חל גם על מה שקרה בעבר"},
+			{Term: "אנכרוניסטי", Meaning: "שאינו תואם את התקופה"},
+		},
+	},
+	"en-basics": {
+		ID:   "en-basics",
+		Name: "English words that appear often",
+		Kind: EnglishTranslations,
+		Words: []Word{
+			{Term: "abundant", Meaning: "שופע, מצוי בכמות רבה"},
+			{Term: "reluctant", Meaning: "מסויג, לא ששׂ"},
+			{Term: "subsequent", Meaning: "עוקב, שבא לאחר מכן"},
+			{Term: "obsolete", Meaning: "מיושן, שיצא משימוש"},
+			{Term: "scarce", Meaning: "נדיר, מועט"},
+			{Term: "diminish", Meaning: "להפחית, להצטמצם"},
+			{Term: "thrive", Meaning: "לשגשג"},
+			{Term: "contemporary", Meaning: "בן זמננו, עכשווי"},
+		},
+	},
+}
+
+// Path of a JSON lines file that imported word lists are persisted to, taken from the `WORD_LISTS_PATH` environment
+// variable.
+//
+// When empty, imported word lists are only kept in memory, and only the built-in lists remain once the server restarts.
+func wordListsPath() string {
+	return os.Getenv("WORD_LISTS_PATH")
+}
+
+func recordWordList(list WordList) error {
+	wordLists[list.ID] = &list
+
+	if path := wordListsPath(); path != "" {
+		return appendJSONLines(path, []WordList{list})
+	}
+	return nil
+}
+
+// Adds the persisted word lists to the built-in ones, where a list imported later replaces any list with the same ID.
+func applyWordLists(imported []WordList) {
+	for _, list := range imported {
+		wordLists[list.ID] = &list
+	}
+}
+
+var InvalidWordList = errors.New("invalid word list")
+
+// Reads a word list from CSV, where every record is a term followed by its meaning.
+//
+// A list must have at least as many distinct meanings as a question has options, so that distractors can be drawn
+// from it.
+func parseWordList(r io.Reader) ([]Word, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", InvalidWordList, err)
+	}
+
+	words := []Word{}
+	terms := map[string]bool{}
+	meanings := map[string]bool{}
+	for _, record := range records {
+		word := Word{Term: strings.TrimSpace(record[0]), Meaning: strings.TrimSpace(record[1])}
+		if word.Term == "" || word.Meaning == "" {
+			return nil, fmt.Errorf("%w: empty term or meaning", InvalidWordList)
+		}
+		if terms[word.Term] {
+			return nil, fmt.Errorf("%w: duplicate term %q", InvalidWordList, word.Term)
+		}
+
+		terms[word.Term] = true
+		meanings[word.Meaning] = true
+		words = append(words, word)
+	}
+
+	if len(meanings) < vocabularyOptions {
+		return nil, fmt.Errorf("%w: at least %d distinct meanings are required", InvalidWordList, vocabularyOptions)
+	}
+
+	return words, nil
+}
+
+// Generates a question asking for the meaning of `word`, with the other options drawn from the rest of the list.
+func generateVocabularyQuestion(rand *rand.Rand, list *WordList, word Word) Question {
+	distractors := []string{}
+	seen := map[string]bool{word.Meaning: true}
+	for _, index := range rand.Perm(len(list.Words)) {
+		meaning := list.Words[index].Meaning
+		if !seen[meaning] {
+			seen[meaning] = true
+			distractors = append(distractors, meaning)
+		}
+	}
+
+	question := Question{ID: "vocabulary:" + list.wordID(word)}
+	if list.Kind == EnglishTranslations {
+		question.Content = fmt.Sprintf("מה התרגום של המילה \"%s\"?", word.Term)
+	} else {
+		question.Content = fmt.Sprintf("מה פירוש המילה \"%s\"?", word.Term)
+	}
+
+	question.Options = make([]string, vocabularyOptions)
+	question.CorrectOption = rand.Intn(len(question.Options))
+	for i := range question.Options {
+		if i == question.CorrectOption {
+			question.Options[i] = word.Meaning
+		} else {
+			question.Options[i] = distractors[0]
+			distractors = distractors[1:]
+		}
+	}
+
+	return question
+}
+
+// Every student's flashcard schedule, by word ID.
+var vocabularySchedules = map[string]map[string]*ReviewCard{}
+
+func vocabularySchedule(student string) map[string]*ReviewCard {
+	schedule, ok := vocabularySchedules[student]
+	if !ok {
+		schedule = map[string]*ReviewCard{}
+		vocabularySchedules[student] = schedule
+	}
+	return schedule
+}
+
+// A student's answer to a word in a vocabulary quiz. Schedules are rebuilt from these when the server starts.
+type VocabularyReview struct {
+	Student string
+	WordID  string
+	Correct bool
+	Time    time.Time
+}
+
+// Path of a JSON lines file that vocabulary reviews are persisted to, taken from the `VOCABULARY_REVIEWS_PATH`
+// environment variable.
+//
+// When empty, vocabulary schedules are only kept in memory.
+func vocabularyReviewsPath() string {
+	return os.Getenv("VOCABULARY_REVIEWS_PATH")
+}
+
+func updateVocabularySchedule(review VocabularyReview) {
+	schedule := vocabularySchedule(review.Student)
+	card, ok := schedule[review.WordID]
+	if !ok {
+		card = newReviewCard(review.Time)
+		schedule[review.WordID] = card
+	}
+	card.Review(reviewQuality(review.Correct), review.Time)
+}
+
+func recordVocabularyReviews(added []VocabularyReview) error {
+	for _, review := range added {
+		updateVocabularySchedule(review)
+	}
+
+	if path := vocabularyReviewsPath(); path != "" {
+		return appendJSONLines(path, added)
+	}
+	return nil
+}
+
+// Replays every review, in the order they were given, to rebuild the schedules.
+func rebuildVocabularySchedules(reviews []VocabularyReview) {
+	sorted := make([]VocabularyReview, len(reviews))
+	copy(sorted, reviews)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	vocabularySchedules = map[string]map[string]*ReviewCard{}
+	for _, review := range sorted {
+		updateVocabularySchedule(review)
+	}
+}
+
+// Chooses the words to quiz: those due for review first, followed by words the student has not studied yet.
+func chooseQuizWords(rand *rand.Rand, list *WordList, schedule map[string]*ReviewCard, now time.Time, size int) []Word {
+	due := []Word{}
+	unseen := []Word{}
+	for _, word := range list.Words {
+		card, ok := schedule[list.wordID(word)]
+		if !ok {
+			unseen = append(unseen, word)
+		} else if card.IsDue(now) {
+			due = append(due, word)
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return schedule[list.wordID(due[i])].Due.Before(schedule[list.wordID(due[j])].Due)
+	})
+	rand.Shuffle(len(unseen), func(i, j int) {
+		unseen[i], unseen[j] = unseen[j], unseen[i]
+	})
+
+	words := append(due, unseen...)
+	return words[:min(len(words), size)]
+}
+
+type VocabularyQuiz struct {
+	Session     string
+	List        *WordList
+	Psychometry Psychometry
+	Retention   Retention
+}
+
+var vocabularyQuizzes = map[string]*VocabularyQuiz{}
+
+func registerVocabulary(e *echo.Echo) {
+	e.GET("/vocabulary", func(c echo.Context) error {
+		lists := []*WordList{}
+		for _, list := range wordLists {
+			lists = append(lists, list)
+		}
+		sort.Slice(lists, func(i, j int) bool {
+			return lists[i].ID < lists[j].ID
+		})
+
+		return c.Render(http.StatusOK, "vocabulary-page", lists)
+	})
+
+	e.GET("/vocabulary/quiz", func(c echo.Context) error {
+		list, ok := wordLists[c.QueryParam("list")]
+		if !ok {
+			return echo.NewHTTPError(http.StatusNotFound, "unknown word list")
+		}
+
+		schedule := vocabularySchedule(studentID(c))
+		now := time.Now()
+		random := rand.New(rand.NewSource(now.UnixNano()))
+
+		section := Section{Kind: wordListSections[list.Kind], Index: 0, IsCounted: true}
+		for _, word := range chooseQuizWords(random, list, schedule, now, vocabularyQuizSize) {
+			section.Questions = append(section.Questions, generateVocabularyQuestion(random, list, word))
+		}
+
+		quiz := &VocabularyQuiz{
+			Session:   uuid.New().String(),
+			List:      list,
+			Retention: calculateRetention(schedule, now),
+		}
+		if len(section.Questions) > 0 {
+			quiz.Psychometry = Psychometry{Sections: []Section{section}}
+		}
+		vocabularyQuizzes[quiz.Session] = quiz
+
+		return c.Render(http.StatusOK, "vocabulary-quiz-page", quiz)
+	})
+
+	e.POST("/vocabulary/answers", func(c echo.Context) error {
+		req := c.Request()
+		if err := req.ParseForm(); err != nil {
+			return err
+		}
+
+		quiz, ok := vocabularyQuizzes[req.Form.Get("session")]
+		if !ok || len(quiz.Psychometry.Sections) == 0 {
+			// TODO: handle this
+			return errors.New("invalid session")
+		}
+
+		answers, err := ParsePsychometryAnswers(req.Form, quiz.Psychometry, Shuffle{})
+		if err != nil {
+			return err
+		}
+
+		delete(vocabularyQuizzes, quiz.Session)
+
+		student := studentID(c)
+		now := time.Now()
+		section := quiz.Psychometry.Sections[0]
+		feedback := questionFeedback(section, answers.Sections[0], answers.Entries[0])
+		reviews := []VocabularyReview{}
+		for _, item := range feedback {
+			reviews = append(reviews, VocabularyReview{
+				Student: student,
+				WordID:  strings.TrimPrefix(item.Question.ID, "vocabulary:"),
+				Correct: item.Correct,
+				Time:    now,
+			})
+		}
+		if err := recordVocabularyReviews(reviews); err != nil {
+			return err
+		}
+
+		result := ReviewResult{Feedback: feedback, Retention: calculateRetention(vocabularySchedule(student), now)}
+		return c.Render(http.StatusCreated, "review-results", result)
+	})
+}
+
+// Imports a word list uploaded as a CSV file, replacing any list with the same ID.
+func importWordList(c echo.Context) error {
+	kind := WordListKind(c.FormValue("kind"))
+	if _, ok := wordListSections[kind]; !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "unknown word list kind")
+	}
+
+	id := strings.TrimSpace(c.FormValue("id"))
+	name := strings.TrimSpace(c.FormValue("name"))
+	if id == "" || name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "missing word list ID or name")
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "missing word list file")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	words, err := parseWordList(file)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := recordWordList(WordList{ID: id, Name: name, Kind: kind, Words: words}); err != nil {
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/vocabulary")
+}
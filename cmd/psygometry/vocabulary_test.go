@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// Test: generated questions have the word's meaning as their correct option, and distinct options from the list
+func TestGenerateVocabularyQuestion_valid(t *testing.T) {
+	valid := func(seed int64, index uint) bool {
+		random := rand.New(rand.NewSource(seed))
+		list := wordLists["he-basics"]
+		word := list.Words[index%uint(len(list.Words))]
+
+		question := generateVocabularyQuestion(random, list, word)
+		if question.Options[question.CorrectOption] != word.Meaning {
+			return false
+		}
+
+		meanings := map[string]bool{}
+		for _, word := range list.Words {
+			meanings[word.Meaning] = true
+		}
+
+		seen := map[string]bool{}
+		for _, option := range question.Options {
+			if seen[option] || !meanings[option] {
+				return false
+			}
+			seen[option] = true
+		}
+		return true
+	}
+
+	if err := quick.Check(valid, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test: word lists without enough distinct meanings for distractors are rejected
+func TestParseWordList(t *testing.T) {
+	words, err := parseWordList(strings.NewReader("a, א\nb, ב\nc, ג\nd, ד\n"))
+	if err != nil || len(words) != 4 || words[0] != (Word{Term: "a", Meaning: "א"}) {
+		t.Errorf("expected a valid word list, got %v (%v)", words, err)
+	}
+
+	invalid := []string{
+		"a, א\nb, ב\nc, ג\n",
+		"a, א\nb, א\nc, א\nd, א\n",
+		"a, א\na, ב\nc, ג\nd, ד\n",
+		"a, א\nb\nc, ג\nd, ד\n",
+	}
+	for _, input := range invalid {
+		if _, err := parseWordList(strings.NewReader(input)); !errors.Is(err, InvalidWordList) {
+			t.Errorf("expected %q to be invalid, got %v", input, err)
+		}
+	}
+}
+
+// Test: words due for review are quizzed before new words, and words that are not due are not quizzed
+func TestChooseQuizWords(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	list := wordLists["en-basics"]
+
+	schedule := map[string]*ReviewCard{}
+	for i, word := range list.Words[:4] {
+		card := newReviewCard(now.Add(-2 * day))
+		if i%2 == 0 {
+			card.Due = now.Add(day)
+		}
+		schedule[list.wordID(word)] = card
+	}
+
+	words := chooseQuizWords(rand.New(rand.NewSource(1)), list, schedule, now, 3)
+
+	if len(words) != 3 || words[0] != list.Words[1] || words[1] != list.Words[3] {
+		t.Errorf("expected the due words first, got %v", words)
+	}
+	if _, ok := schedule[list.wordID(words[2])]; ok {
+		t.Errorf("expected a new word after the due words, got %v", words[2])
+	}
+}
+
+// Test: schedules rebuilt from the persisted reviews are the schedules the reviews built as they were recorded
+func TestRebuildVocabularySchedules(t *testing.T) {
+	defer func(original map[string]map[string]*ReviewCard) {
+		vocabularySchedules = original
+	}(vocabularySchedules)
+	vocabularySchedules = map[string]map[string]*ReviewCard{}
+
+	path := filepath.Join(t.TempDir(), "vocabulary.jsonl")
+	t.Setenv("VOCABULARY_REVIEWS_PATH", path)
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 6 {
+		review := VocabularyReview{Student: fmt.Sprint(i % 2), WordID: fmt.Sprint(i % 3), Correct: i%4 != 0, Time: now.Add(time.Duration(i) * day)}
+		if err := recordVocabularyReviews([]VocabularyReview{review}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recorded := vocabularySchedules
+
+	reviews, err := loadJSONLines[VocabularyReview](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rebuildVocabularySchedules(reviews)
+
+	if len(recorded) != 2 || !reflect.DeepEqual(vocabularySchedules, recorded) {
+		t.Errorf("expected the recorded schedules %v, got %v", recorded, vocabularySchedules)
+	}
+}
+
+// Test: imported word lists are loaded back from the persisted lists, along with the built-in ones
+func TestApplyWordLists(t *testing.T) {
+	defer func(original map[string]*WordList) {
+		wordLists = original
+	}(wordLists)
+	wordLists = map[string]*WordList{"he-basics": wordLists["he-basics"]}
+
+	path := filepath.Join(t.TempDir(), "word-lists.jsonl")
+	t.Setenv("WORD_LISTS_PATH", path)
+
+	words := wordLists["he-basics"].Words
+	for _, list := range []WordList{
+		{ID: "a", Name: "first", Kind: HebrewDefinitions, Words: words},
+		{ID: "b", Name: "second", Kind: HebrewDefinitions, Words: words},
+		{ID: "a", Name: "replaced", Kind: HebrewDefinitions, Words: words[1:]},
+	} {
+		if err := recordWordList(list); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recorded := wordLists
+
+	loaded, err := loadJSONLines[WordList](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wordLists = map[string]*WordList{"he-basics": recorded["he-basics"]}
+	applyWordLists(loaded)
+
+	if !reflect.DeepEqual(wordLists, recorded) || wordLists["a"].Name != "replaced" {
+		t.Errorf("expected the imported lists %v, got %v", recorded, wordLists)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"math"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// The number of topics recommended for practice after an exam.
+const recommendedTopics = 3
+
+type TopicReport struct {
+	Kind     SectionKind
+	Topic    Topic
+	Name     string
+	Answered int
+	Correct  int
+	Accuracy float64
+	// Total time spent on the topic's questions, and how many of them were timed.
+	Time  time.Duration
+	Timed int
+	// Accuracy on the topic in the student's previous attempts, when there are any.
+	HasHistory         bool
+	HistoricalAccuracy float64
+}
+
+func (r TopicReport) Percent() int {
+	return int(math.Round(r.Accuracy * 100))
+}
+
+func (r TopicReport) HistoricalPercent() int {
+	return int(math.Round(r.HistoricalAccuracy * 100))
+}
+
+// The average time spent on each of the topic's questions, or an empty string if none were timed.
+func (r TopicReport) AverageTime() string {
+	if r.Timed == 0 {
+		return ""
+	}
+	return formatSeconds(r.Time / time.Duration(r.Timed))
+}
+
+// Change in accuracy from previous attempts, in percentage points.
+func (r TopicReport) Delta() int {
+	return r.Percent() - r.HistoricalPercent()
+}
+
+// A link to a drill of the topic.
+func (r TopicReport) DrillURL() string {
+	query := url.Values{}
+	query.Set("kind", string(r.Kind))
+	query.Set("topic", string(r.Topic))
+	return "/drill/start?" + query.Encode()
+}
+
+type WeaknessReport struct {
+	Topics      []TopicReport
+	Recommended []TopicReport
+}
+
+type topicKey struct {
+	kind  SectionKind
+	topic Topic
+}
+
+// Groups the answers (and their timings) of an attempt by topic, and compares them to the student's answers in previous attempts.
+//
+// Only counted sections are considered, and the `recommendedTopics` topics with the lowest accuracy are recommended
+// for practice.
+func diagnoseWeaknesses(psychometry Psychometry, answers PsychometryAnswers, attempt string, history []Response) WeaknessReport {
+	reports := map[topicKey]*TopicReport{}
+	order := []topicKey{}
+
+	for _, kind := range []SectionKind{V, Q, E} {
+		answerSections := answers.GetSections(psychometry, kind)
+		timingSections := answers.GetTimings(psychometry, kind)
+		entrySections := answers.GetEntries(psychometry, kind)
+		for i, section := range psychometry.GetSections(kind) {
+			for j, question := range section.Questions {
+				if question.Topic == "" {
+					continue
+				}
+
+				key := topicKey{kind, question.Topic}
+				report, ok := reports[key]
+				if !ok {
+					report = &TopicReport{Kind: kind, Topic: question.Topic, Name: topicNames[question.Topic]}
+					reports[key] = report
+					order = append(order, key)
+				}
+
+				report.Answered += 1
+				if question.IsCorrect(answerSections[i][j], entrySections[i][j]) {
+					report.Correct += 1
+				}
+				if timingSections[i][j] > 0 {
+					report.Time += timingSections[i][j]
+					report.Timed += 1
+				}
+			}
+		}
+	}
+
+	answered := map[topicKey]int{}
+	correct := map[topicKey]int{}
+	for _, response := range history {
+		if response.Attempt == attempt {
+			continue
+		}
+		question, _, ok := findQuestion(response.QuestionID)
+		if !ok {
+			continue
+		}
+
+		key := topicKey{response.Kind, question.Topic}
+		answered[key] += 1
+		if response.Correct {
+			correct[key] += 1
+		}
+	}
+
+	weaknesses := WeaknessReport{Topics: []TopicReport{}, Recommended: []TopicReport{}}
+	for _, key := range order {
+		report := reports[key]
+		report.Accuracy = float64(report.Correct) / float64(report.Answered)
+		if answered[key] > 0 {
+			report.HasHistory = true
+			report.HistoricalAccuracy = float64(correct[key]) / float64(answered[key])
+		}
+		weaknesses.Topics = append(weaknesses.Topics, *report)
+	}
+
+	weakest := make([]TopicReport, len(weaknesses.Topics))
+	copy(weakest, weaknesses.Topics)
+	sort.SliceStable(weakest, func(i, j int) bool {
+		if weakest[i].Accuracy != weakest[j].Accuracy {
+			return weakest[i].Accuracy < weakest[j].Accuracy
+		}
+		return weakest[i].Answered > weakest[j].Answered
+	})
+	for _, report := range weakest[:min(len(weakest), recommendedTopics)] {
+		if report.Accuracy < 1 {
+			weaknesses.Recommended = append(weaknesses.Recommended, report)
+		}
+	}
+
+	return weaknesses
+}
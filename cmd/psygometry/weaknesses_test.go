@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+)
+
+// Test: topics are reported with their accuracy, and the weakest topics are recommended
+func TestDiagnoseWeaknesses(t *testing.T) {
+	psychometry := generateFakeData()
+	answers := newPsychometryAnswers(psychometry)
+
+	// Answer every question correctly, except those about analogies and geometry
+	for i, section := range psychometry.Sections {
+		for j, question := range section.Questions {
+			if question.Topic != Analogies && question.Topic != Geometry {
+				answers.Sections[i][j] = question.CorrectOption
+			}
+		}
+	}
+
+	history := []Response{
+		{Attempt: "previous", QuestionID: "fake-0-0", Kind: V, Correct: true},
+		{Attempt: "current", QuestionID: "fake-0-0", Kind: V, Correct: false},
+	}
+
+	report := diagnoseWeaknesses(psychometry, answers, "current", history)
+
+	if len(report.Recommended) != 2 {
+		t.Fatalf("expected two recommended topics, got %v", report.Recommended)
+	}
+	for _, recommended := range report.Recommended {
+		if recommended.Topic != Analogies && recommended.Topic != Geometry {
+			t.Errorf("unexpected recommended topic %q", recommended.Topic)
+		}
+	}
+
+	for _, topic := range report.Topics {
+		if topic.Topic == Analogies && (!topic.HasHistory || topic.HistoricalAccuracy != 1 || topic.Delta() != -100) {
+			t.Errorf("expected analogies to be compared to the previous attempt only, got %v", topic)
+		}
+		if topic.Topic == Algebra && topic.HasHistory {
+			t.Errorf("expected algebra to have no history, got %v", topic)
+		}
+	}
+}
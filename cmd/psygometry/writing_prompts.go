@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+type WritingGenre string
+
+const (
+	// Taking and defending a position on a general topic.
+	Argumentative WritingGenre = "argumentative"
+	// Responding with an opinion to a quoted passage.
+	QuotedPassage WritingGenre = "quoted-passage"
+	// Weighing the two sides of a dilemma and deciding between them.
+	Dilemma WritingGenre = "dilemma"
+)
+
+type WritingDifficulty int
+
+const (
+	DifficultyEasy WritingDifficulty = iota + 1
+	DifficultyMedium
+	DifficultyHard
+)
+
+func (d WritingDifficulty) IsEasy() bool   { return d == DifficultyEasy }
+func (d WritingDifficulty) IsMedium() bool { return d == DifficultyMedium }
+func (d WritingDifficulty) IsHard() bool   { return d == DifficultyHard }
+
+type WritingPrompt struct {
+	ID         string
+	Genre      WritingGenre
+	Difficulty WritingDifficulty
+	// Text given to the student before the task itself, such as a quoted passage or the details of a dilemma.
+	// May be empty.
+	Background string
+	Task       string
+	// Task-specific criteria for the grader, in addition to those of the genre.
+	Guidance []string
+}
+
+var genreGuidance = map[WritingGenre][]string{
+	Argumentative: {
+		"The essay should take a clear position on the topic, rather than only describing it.",
+		"The position should be supported by relevant arguments and examples, and at least one opposing argument should be addressed.",
+	},
+	QuotedPassage: {
+		"The essay should show that the student understood the quoted passage, without merely restating it.",
+		"The student's own opinion on the passage should be stated clearly and supported by arguments.",
+	},
+	Dilemma: {
+		"The essay should present the considerations on both sides of the dilemma fairly.",
+		"The essay should reach a clear decision, and explain why the chosen considerations outweigh the others.",
+	},
+}
+
+var genreNames = map[WritingGenre]string{
+	Argumentative: "an argumentative essay",
+	QuotedPassage: "an opinion on a quoted passage",
+	Dilemma:       "a dilemma",
+}
+
+// The criteria the grader should use for this prompt: those of its genre, followed by its own.
+func (p WritingPrompt) Criteria() []string {
+	criteria := []string{}
+	criteria = append(criteria, genreGuidance[p.Genre]...)
+	criteria = append(criteria, p.Guidance...)
+	return criteria
+}
+
+// Describes the prompt for the grader, as it was presented to the student.
+func (p WritingPrompt) describe() string {
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "The task is %s.\n", genreNames[p.Genre])
+	if p.Background != "" {
+		fmt.Fprintf(&builder, "\nBackground:\n%s\n", p.Background)
+	}
+	fmt.Fprintf(&builder, "\nTask:\n%s", p.Task)
+
+	return builder.String()
+}
+
+var writingPrompts = []WritingPrompt{
+	{
+		ID:         "cinema-storytelling",
+		Genre:      Argumentative,
+		Difficulty: DifficultyEasy,
+		Task:       "נא לכתוב חיבור על החשיבות של סיפור סיפורים בקולנוע המודרני.",
+		Guidance: []string{
+			"Essays that only list favourite films, without arguing for the place of storytelling in them, should receive a low content score.",
+		},
+	},
+	{
+		ID:         "remote-work",
+		Genre:      Argumentative,
+		Difficulty: DifficultyMedium,
+		Task:       "יש הטוענים כי עבודה מהבית פוגעת ביצירתיות ובשיתוף הפעולה בין עובדים, ויש הטוענים כי היא מגבירה את הפריון. מהי עמדתך? נמק.",
+		Guidance: []string{
+			"The essay should refer to both creativity and productivity, not only to one of them.",
+		},
+	},
+	{
+		ID:         "memory-and-history",
+		Genre:      QuotedPassage,
+		Difficulty: DifficultyMedium,
+		Background: "\"מי שאינו זוכר את העבר, נידון לחזור עליו.\" (ג'ורג' סנטיאנה)",
+		Task:       "הסבר את דברי הציטוט, וכתוב האם אתה מסכים איתם. נמק את עמדתך ובסס אותה על דוגמאות.",
+		Guidance: []string{
+			"Examples may be historical or personal, but should be connected explicitly to the quote.",
+		},
+	},
+	{
+		ID:         "technology-and-loneliness",
+		Genre:      QuotedPassage,
+		Difficulty: DifficultyEasy,
+		Background: "\"הטכנולוגיה קירבה אותנו לאלה שרחוקים מאיתנו, והרחיקה אותנו מאלה שקרובים אלינו.\"",
+		Task:       "האם אתה מסכים עם הטענה המובאת בציטוט? נמק את עמדתך.",
+		Guidance: []string{
+			"The essay should address both halves of the quote.",
+		},
+	},
+	{
+		ID:         "city-budget",
+		Genre:      Dilemma,
+		Difficulty: DifficultyMedium,
+		Background: "עירייה קיבלה תקציב חד-פעמי, ועליה להחליט אם להשקיע אותו בבניית פארק ציבורי גדול במרכז העיר, או בשיפוץ בתי הספר בשכונות המוחלשות שבה.",
+		Task:       "אילו שיקולים צריכים להנחות את העירייה בהחלטתה? מה היית מחליט במקומה? נמק.",
+		Guidance: []string{
+			"The essay should consider who benefits from each option, and over what period of time.",
+		},
+	},
+	{
+		ID:         "doctor-confidentiality",
+		Genre:      Dilemma,
+		Difficulty: DifficultyHard,
+		Background: "רופא משפחה מגלה שאחד ממטופליו, נהג אוטובוס, סובל ממחלה העלולה לגרום לו לאבד את ההכרה באופן פתאומי. המטופל מבקש מהרופא שלא לדווח על כך למשרד הרישוי, מחשש שיאבד את פרנסתו.",
+		Task:       "האם על הרופא לכבד את בקשת המטופל? הצג את השיקולים לכאן ולכאן, וכתוב מה עמדתך.",
+		Guidance: []string{
+			"The essay should weigh medical confidentiality against public safety, rather than ignoring either.",
+		},
+	},
+}
+
+// Finds a prompt in the library by its ID.
+func GetWritingPrompt(id string) (WritingPrompt, bool) {
+	for _, prompt := range writingPrompts {
+		if prompt.ID == id {
+			return prompt, true
+		}
+	}
+
+	return WritingPrompt{}, false
+}
+
+// Draws a prompt from the library, optionally restricted to a single genre and difficulty (when `genre` is empty
+// or `difficulty` is 0, any genre or difficulty may be drawn). Reports false when no prompt matches.
+func RandomWritingPrompt(rand *rand.Rand, genre WritingGenre, difficulty WritingDifficulty) (WritingPrompt, bool) {
+	candidates := []WritingPrompt{}
+	for _, prompt := range writingPrompts {
+		if (genre == "" || prompt.Genre == genre) && (difficulty == 0 || prompt.Difficulty == difficulty) {
+			candidates = append(candidates, prompt)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return WritingPrompt{}, false
+	}
+
+	return candidates[rand.Intn(len(candidates))], true
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"testing/quick"
+)
+
+// Test: every prompt in the library has a unique ID, a known genre, and a task
+func TestWritingPrompts_valid(t *testing.T) {
+	ids := map[string]bool{}
+
+	for _, prompt := range writingPrompts {
+		if prompt.ID == "" || ids[prompt.ID] {
+			t.Errorf("prompt ID %q is missing or duplicated", prompt.ID)
+		}
+		ids[prompt.ID] = true
+
+		if _, ok := genreGuidance[prompt.Genre]; !ok {
+			t.Errorf("prompt %q has unknown genre %q", prompt.ID, prompt.Genre)
+		}
+
+		if prompt.Difficulty < DifficultyEasy || prompt.Difficulty > DifficultyHard {
+			t.Errorf("prompt %q has unknown difficulty %d", prompt.ID, prompt.Difficulty)
+		}
+
+		if prompt.Task == "" {
+			t.Errorf("prompt %q has no task", prompt.ID)
+		}
+
+		if len(prompt.Criteria()) <= len(prompt.Guidance) {
+			t.Errorf("prompt %q does not include the guidance of its genre", prompt.ID)
+		}
+	}
+}
+
+// Test: drawing a prompt of a certain genre always returns a prompt of that genre, and none when nothing matches
+func TestRandomWritingPrompt_genre(t *testing.T) {
+	genres := []WritingGenre{Argumentative, QuotedPassage, Dilemma}
+
+	ofGenre := func(seed int64, index uint) bool {
+		genre := genres[index%uint(len(genres))]
+		prompt, ok := RandomWritingPrompt(rand.New(rand.NewSource(seed)), genre, 0)
+		return ok && prompt.Genre == genre
+	}
+
+	if err := quick.Check(ofGenre, nil); err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := RandomWritingPrompt(rand.New(rand.NewSource(1)), "unknown", 0); ok {
+		t.Error("expected no prompt to match an unknown genre")
+	}
+}